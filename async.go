@@ -0,0 +1,198 @@
+package rotate
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// FullPolicy is a behavior of the AsyncWriter when its queue is full
+type FullPolicy int
+
+// FullPolicies
+const (
+	// FullBlock blocks the Write call until the queue has room
+	FullBlock FullPolicy = iota
+	// FullDropNewest drops the data of the Write call
+	FullDropNewest
+	// FullDropOldest drops the oldest queued data until the queue has room
+	FullDropOldest
+)
+
+// DefaultQueueSize is the default max bytes of the queue of the AsyncWriter
+const DefaultQueueSize = 1024 * 1024
+
+type asyncOption struct {
+	queueSize  int
+	fullPolicy FullPolicy
+}
+
+// AsyncOptionFunc let you change AsyncWriter behavior
+type AsyncOptionFunc func(o *asyncOption)
+
+func (o *asyncOption) apply(opts ...AsyncOptionFunc) {
+	o.queueSize = DefaultQueueSize
+	o.fullPolicy = FullBlock
+	for _, fn := range opts {
+		fn(o)
+	}
+}
+
+// WithQueueSize let you change the max bytes of the queue.
+// A Write call larger than the size is still queued if the queue is empty
+func WithQueueSize(v int) AsyncOptionFunc {
+	return func(o *asyncOption) {
+		o.queueSize = v
+	}
+}
+
+// WithFullPolicy let you change the behavior when the queue is full
+func WithFullPolicy(v FullPolicy) AsyncOptionFunc {
+	return func(o *asyncOption) {
+		o.fullPolicy = v
+	}
+}
+
+// AsyncWriter is a Writer which queues the writes in memory and writes them to the underlying Writer on a background goroutine,
+// so that the Write calls are not blocked by the disk stalls
+type AsyncWriter struct {
+	w   *Writer
+	opt asyncOption
+
+	mu sync.Mutex
+	// notEmpty is signaled when the data is queued or the AsyncWriter is closed
+	notEmpty *sync.Cond
+	// notFull is broadcast when the queue is drained or the AsyncWriter is closed
+	notFull *sync.Cond
+	queue   [][]byte
+	// size is the total bytes of the queue
+	size int
+	// writing reports whether the drained data is being written to the underlying Writer
+	writing bool
+	closed  bool
+	// done is closed when the background goroutine exits
+	done chan struct{}
+
+	droppedWrites int64
+	droppedBytes  int64
+}
+
+// NewAsyncWriter creates a *rotate.AsyncWriter which writes to the w
+func NewAsyncWriter(w *Writer, opts ...AsyncOptionFunc) *AsyncWriter {
+	var opt asyncOption
+	opt.apply(opts...)
+
+	a := &AsyncWriter{
+		w:    w,
+		opt:  opt,
+		done: make(chan struct{}),
+	}
+	a.notEmpty = sync.NewCond(&a.mu)
+	a.notFull = sync.NewCond(&a.mu)
+	go a.drain()
+	return a
+}
+
+// Write implements io.Writer. It copies the p into the queue and returns len(p) without waiting for the write to the file,
+// even if the p is dropped by the FullPolicy. The errors of the writes to the file are reported to the error handler of the Writer
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return 0, ErrClosed
+	}
+	for len(a.queue) > 0 && a.size+len(p) > a.opt.queueSize {
+		switch a.opt.fullPolicy {
+		case FullDropNewest:
+			a.drop(len(p))
+			return len(p), nil
+		case FullDropOldest:
+			oldest := a.queue[0]
+			a.queue[0] = nil
+			a.queue = a.queue[1:]
+			a.size -= len(oldest)
+			a.drop(len(oldest))
+		default:
+			a.notFull.Wait()
+			if a.closed {
+				return 0, ErrClosed
+			}
+		}
+	}
+	b := make([]byte, len(p))
+	copy(b, p)
+	a.queue = append(a.queue, b)
+	a.size += len(b)
+	a.notEmpty.Signal()
+	return len(p), nil
+}
+
+func (a *AsyncWriter) drop(n int) {
+	atomic.AddInt64(&a.droppedWrites, 1)
+	atomic.AddInt64(&a.droppedBytes, int64(n))
+}
+
+// DroppedWrites returns the number of the Write calls dropped by the FullPolicy
+func (a *AsyncWriter) DroppedWrites() int64 {
+	return atomic.LoadInt64(&a.droppedWrites)
+}
+
+// DroppedBytes returns the bytes dropped by the FullPolicy
+func (a *AsyncWriter) DroppedBytes() int64 {
+	return atomic.LoadInt64(&a.droppedBytes)
+}
+
+// Flush waits for the queued data to be written to the underlying Writer
+func (a *AsyncWriter) Flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for len(a.queue) > 0 || a.writing {
+		a.notFull.Wait()
+	}
+}
+
+// Close writes the queued data and closes the underlying Writer
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	a.closed = true
+	a.notEmpty.Broadcast()
+	a.notFull.Broadcast()
+	a.mu.Unlock()
+
+	<-a.done
+	return a.w.Close()
+}
+
+// drain writes the queued data to the underlying Writer until the AsyncWriter is closed and the queue is empty
+func (a *AsyncWriter) drain() {
+	defer close(a.done)
+
+	for {
+		a.mu.Lock()
+		for len(a.queue) == 0 && !a.closed {
+			a.notEmpty.Wait()
+		}
+		if len(a.queue) == 0 {
+			a.mu.Unlock()
+			return
+		}
+		batch := a.queue
+		a.queue = nil
+		a.size = 0
+		a.writing = true
+		a.notFull.Broadcast()
+		a.mu.Unlock()
+
+		for _, b := range batch {
+			if _, err := a.w.Write(b); err != nil {
+				a.w.reportError(err)
+			}
+		}
+
+		a.mu.Lock()
+		a.writing = false
+		a.notFull.Broadcast()
+		a.mu.Unlock()
+	}
+}
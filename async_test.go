@@ -0,0 +1,141 @@
+package rotate
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAsyncWriter(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	w, err := NewWriter(string(dir), "test.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAsyncWriter(w, WithQueueSize(10))
+	if err := nGroutinesDo(10, func() error { return writeNCount(a, "a", 100) }); err != nil {
+		t.Fatal(err)
+	}
+	a.Flush()
+	if err := containsNCount("a", 1000, dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeNCount(a, "b", 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("b", 100, dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Write([]byte("c")); err != ErrClosed {
+		t.Errorf("got %v, want ErrClosed", err)
+	}
+	if a.DroppedWrites() != 0 || a.DroppedBytes() != 0 {
+		t.Errorf("dropped got %v writes %v bytes, want 0", a.DroppedWrites(), a.DroppedBytes())
+	}
+}
+
+func TestAsyncWriter_FullPolicy(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name          string
+		policy        FullPolicy
+		droppedWrites int64
+		droppedBytes  int64
+		want          string
+	}{
+		{
+			name:          "drop newest",
+			policy:        FullDropNewest,
+			droppedWrites: 1,
+			droppedBytes:  1,
+			want:          "abbbbbbbbbb",
+		},
+		{
+			name:          "drop oldest",
+			policy:        FullDropOldest,
+			droppedWrites: 1,
+			droppedBytes:  10,
+			want:          "ac",
+		},
+		{
+			name:   "block",
+			policy: FullBlock,
+			want:   "abbbbbbbbbbc",
+		},
+	}
+	for _, te := range tt {
+		te := te
+		t.Run(te.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := createTmpDir()
+			defer dir.removeAll()
+
+			// stall the background writes by the hook of the synchronous rotation
+			entered := make(chan struct{}, 1)
+			release := make(chan struct{})
+			stall := func(RotateEvent) error {
+				select {
+				case entered <- struct{}{}:
+				default:
+				}
+				<-release
+				return nil
+			}
+			w, err := NewWriter(string(dir), "test.log", WithSizeBasedPolicy(1), WithKeeps(20), WithSyncRotation(), WithBeforeRotate(stall))
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := NewAsyncWriter(w, WithQueueSize(10), WithFullPolicy(te.policy))
+			defer a.Close()
+
+			a.Write([]byte("a"))
+			<-entered
+			a.Write([]byte("bbbbbbbbbb"))
+
+			written := make(chan struct{})
+			go func() {
+				a.Write([]byte("c"))
+				close(written)
+			}()
+			select {
+			case <-written:
+				if te.policy == FullBlock {
+					t.Fatal("the Write call is not blocked")
+				}
+			case <-time.After(100 * time.Millisecond):
+				if te.policy != FullBlock {
+					t.Fatal("the Write call is blocked")
+				}
+			}
+			if got := a.DroppedWrites(); got != te.droppedWrites {
+				t.Errorf("dropped writes got %v, want %v", got, te.droppedWrites)
+			}
+			if got := a.DroppedBytes(); got != te.droppedBytes {
+				t.Errorf("dropped bytes got %v, want %v", got, te.droppedBytes)
+			}
+
+			close(release)
+			<-written
+			a.Flush()
+			var got []string
+			for _, f := range []string{"test.log.3", "test.log.2", "test.log.1", "test.log"} {
+				b, _ := ioutil.ReadFile(filepath.Join(string(dir), f))
+				got = append(got, string(b))
+			}
+			if s := strings.Join(got, ""); s != te.want {
+				t.Errorf("got %v, want %v", s, te.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package rotate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Command is an external command run after each rotation, like the postrotate script of logrotate
+type Command struct {
+	// Args is the program and its arguments. The path of the rotated file is appended as the last argument
+	Args []string
+	// Env is the additional environment variables in the form "key=value", appended to the environment of the process
+	Env []string
+	// Timeout kills the command if it does not exit within the duration. 0 means no timeout
+	Timeout time.Duration
+}
+
+// commandWaitDelay is the max duration to wait for the output of the command after it exited or was killed
+const commandWaitDelay = time.Second
+
+// CommandError is the error of the Command which failed to start or exited with the non-zero status
+type CommandError struct {
+	// Args is the arguments of the command, including the path of the rotated file
+	Args []string
+	// ExitCode is the exit status of the command, or -1 if it did not exit normally (e.g. killed by the timeout)
+	ExitCode int
+	// Stderr is the standard error output of the command
+	Stderr string
+	// Err is the underlying error. It is context.DeadlineExceeded if the command timed out
+	Err error
+}
+
+func (e *CommandError) Error() string {
+	s := strings.Join(e.Args, " ") + ": " + e.Err.Error()
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		s += ": " + stderr
+	}
+	return s
+}
+
+// Unwrap returns the underlying error
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// run runs the command with the rotated path and waits for it to exit
+func (c Command) run(rotated string) error {
+	if len(c.Args) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	args := append(append([]string{}, c.Args...), rotated)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), c.Env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = commandWaitDelay
+
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+	cerr := &CommandError{Args: args, ExitCode: -1, Stderr: stderr.String(), Err: err}
+	var eerr *exec.ExitError
+	if errors.As(err, &eerr) {
+		cerr.ExitCode = eerr.ExitCode()
+	}
+	if ctx.Err() != nil {
+		cerr.Err = ctx.Err()
+	}
+	return newRotateError("command", rotated, cerr)
+}
@@ -0,0 +1,96 @@
+//go:build !windows
+// +build !windows
+
+package rotate
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriter_PostRotateCommand(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	out := filepath.Join(string(dir), "out")
+	cmd := Command{
+		Args: []string{"sh", "-c", `echo "$1" > "$OUT"`, "sh"},
+		Env:  []string{"OUT=" + out},
+	}
+	w, err := NewWriter(string(dir), "test.log", WithPostRotateCommand(cmd),
+		WithErrorHandler(func(err error) { t.Errorf("unexpected error %+v", err) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err := writeNBytes(w, "a", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(string(b)), filepath.Join(string(dir), "test.log.1"); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCommand_run(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		cmd      Command
+		exitCode int
+		stderr   string
+		err      error
+	}{
+		{
+			name:     "exit status",
+			cmd:      Command{Args: []string{"sh", "-c", `echo "failed $1" >&2; exit 3`, "sh"}},
+			exitCode: 3,
+			stderr:   "failed test.log.1",
+		},
+		{
+			name:     "timeout",
+			cmd:      Command{Args: []string{"sh", "-c", "sleep 10", "sh"}, Timeout: 100 * time.Millisecond},
+			exitCode: -1,
+			err:      context.DeadlineExceeded,
+		},
+	}
+	for _, te := range tt {
+		te := te
+		t.Run(te.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := te.cmd.run("test.log.1")
+			if !errors.Is(err, ErrCommand) {
+				t.Fatalf("unexpected error %+v", err)
+			}
+			var cerr *CommandError
+			if !errors.As(err, &cerr) {
+				t.Fatalf("want *CommandError, got %+v", err)
+			}
+			if cerr.ExitCode != te.exitCode {
+				t.Errorf("exit code got %v, want %v", cerr.ExitCode, te.exitCode)
+			}
+			if strings.TrimSpace(cerr.Stderr) != te.stderr {
+				t.Errorf("stderr got %q, want %q", cerr.Stderr, te.stderr)
+			}
+			if te.err != nil && !errors.Is(err, te.err) {
+				t.Errorf("got %+v, want %+v", err, te.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/kei2100/rotate/internal/file"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressor compresses the rotated files
+type Compressor interface {
+	// Extension returns the file extension of the compressed file. e.g. ".gz"
+	Extension() string
+	// NewWriter returns a WriteCloser which writes the compressed data to w
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// Built-in compressors with the default compression level
+var (
+	Gzip   Compressor = GzipCompressor{}
+	Zstd   Compressor = ZstdCompressor{}
+	Snappy Compressor = SnappyCompressor{}
+)
+
+// GzipCompressor is a Compressor of the gzip format
+type GzipCompressor struct {
+	// Level is the compression level of the compress/gzip package. 0 means gzip.DefaultCompression
+	Level int
+}
+
+// Extension implements Compressor
+func (c GzipCompressor) Extension() string {
+	return ".gz"
+}
+
+// NewWriter implements Compressor
+func (c GzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+// ZstdCompressor is a Compressor of the zstd format
+type ZstdCompressor struct {
+	// Level is the zstd compression level (1-22). 0 means the default level
+	Level int
+}
+
+// Extension implements Compressor
+func (c ZstdCompressor) Extension() string {
+	return ".zst"
+}
+
+// NewWriter implements Compressor
+func (c ZstdCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	var opts []zstd.EOption
+	if c.Level != 0 {
+		opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(c.Level)))
+	}
+	return zstd.NewWriter(w, opts...)
+}
+
+// SnappyCompressor is a Compressor of the snappy framing format
+type SnappyCompressor struct{}
+
+// Extension implements Compressor
+func (c SnappyCompressor) Extension() string {
+	return ".sz"
+}
+
+// NewWriter implements Compressor
+func (c SnappyCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return s2.NewWriter(w, s2.WriterSnappyCompat()), nil
+}
+
+// replaceCompressed renames the tmp to the dst and removes the path
+func replaceCompressed(path, tmp, dst string) error {
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return newRotateError("rename", tmp, err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return newRotateError("remove", path, err)
+	}
+	return nil
+}
+
+func writeCompressed(src, dst string, c Compressor, perm os.FileMode) error {
+	in, err := file.OpenFile(src, os.O_RDONLY, 0)
+	if err != nil {
+		return newRotateError("open", src, err)
+	}
+	defer in.Close()
+	out, err := file.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return newRotateError("open", dst, err)
+	}
+	cw, err := c.NewWriter(out)
+	if err != nil {
+		out.Close()
+		return newRotateError("compress", src, err)
+	}
+	if _, err := io.Copy(cw, in); err != nil {
+		cw.Close()
+		out.Close()
+		return newRotateError("compress", src, err)
+	}
+	if err := cw.Close(); err != nil {
+		out.Close()
+		return newRotateError("compress", src, err)
+	}
+	if err := out.Close(); err != nil {
+		return newRotateError("close", dst, err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package rotate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestWriter_compressRotatedFile(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		compressor Compressor
+		newReader  func(r io.Reader) (io.Reader, error)
+	}{
+		{
+			compressor: Gzip,
+			newReader:  func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		},
+		{
+			compressor: GzipCompressor{Level: gzip.BestCompression},
+			newReader:  func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		},
+		{
+			compressor: Zstd,
+			newReader:  func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+		},
+		{
+			compressor: ZstdCompressor{Level: 19},
+			newReader:  func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+		},
+		{
+			compressor: Snappy,
+			newReader:  func(r io.Reader) (io.Reader, error) { return s2.NewReader(r), nil },
+		},
+	}
+	want := bytes.Repeat([]byte("abcdefg\n"), 1000)
+	for _, te := range tt {
+		dir := createTmpDir()
+		defer dir.removeAll()
+
+		path := filepath.Join(string(dir), "test.log.1")
+		if err := ioutil.WriteFile(path, want, 0600); err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewWriter(string(dir), "test.log", WithCompression(te.compressor), WithPermission(0600))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.compressRotatedFile(path, w.opt); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%T: %s is not removed", te.compressor, path)
+		}
+		if _, err := os.Stat(path + te.compressor.Extension() + ".tmp"); !os.IsNotExist(err) {
+			t.Errorf("%T: the temporary file is not removed", te.compressor)
+		}
+		f, err := os.Open(path + te.compressor.Extension())
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := te.newReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(r)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%T: decompressed content does not match", te.compressor)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package rotate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a Schedule of the standard 5-field cron expression
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// whether the day of month or the day of week field starts with "*"
+	domStar, dowStar bool
+	loc              *time.Location
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	cronMonthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	cronDowNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+// ParseCron parses the standard 5-field cron expression (minute hour day-of-month month day-of-week) into the Schedule.
+// e.g. "0 */6 * * *", "30 0 * * mon-fri", "@daily".
+// The expression is evaluated in time.Local, unless it is prefixed with "CRON_TZ=<location> " or "TZ=<location> "
+func ParseCron(expr string) (Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	loc := time.Local
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		i := strings.IndexAny(spec, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("rotate: invalid cron expression %q", expr)
+		}
+		name := spec[strings.Index(spec, "=")+1 : i]
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("rotate: invalid cron expression %q: %+v", expr, err)
+		}
+		loc = l
+		spec = strings.TrimSpace(spec[i:])
+	}
+	if m, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = m
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("rotate: invalid cron expression %q: want 5 fields, got %d", expr, len(fields))
+	}
+
+	s := &cronSchedule{loc: loc}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("rotate: invalid cron expression %q: minute: %+v", expr, err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("rotate: invalid cron expression %q: hour: %+v", expr, err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("rotate: invalid cron expression %q: day of month: %+v", expr, err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("rotate: invalid cron expression %q: month: %+v", expr, err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7, cronDowNames); err != nil {
+		return nil, fmt.Errorf("rotate: invalid cron expression %q: day of week: %+v", expr, err)
+	}
+	// 7 is also Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = strings.HasPrefix(fields[2], "*")
+	s.dowStar = strings.HasPrefix(fields[4], "*")
+	if s.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("rotate: invalid cron expression %q: never matches", expr)
+	}
+	return s, nil
+}
+
+// e.g. "*", "5", "1-5", "*/15", "10-50/10", "mon,wed,fri"
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+			rng, step = part[:i], n
+		}
+		var lo, hi int
+		switch {
+		case rng == "*":
+			lo, hi = min, max
+		case strings.Contains(rng, "-"):
+			i := strings.Index(rng, "-")
+			var err error
+			if lo, err = parseCronValue(rng[:i], names); err != nil {
+				return 0, err
+			}
+			if hi, err = parseCronValue(rng[i+1:], names); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := parseCronValue(rng, names)
+			if err != nil {
+				return 0, err
+			}
+			lo, hi = v, v
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+// Next implements Schedule. It returns the zero time if no time matches within 5 years.
+// The time in the gap of the DST transition is shifted by the gap like Daily, and the time repeated by the transition matches once
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.In(s.loc)
+	limit := t.Year() + 5
+
+	for i := 0; ; i++ {
+		// the noon always exists regardless of the DST transition
+		day := time.Date(t.Year(), t.Month(), t.Day()+i, 12, 0, 0, 0, s.loc)
+		if day.Year() > limit {
+			return time.Time{}
+		}
+		if s.month&(1<<uint(day.Month())) == 0 || !s.dayMatches(day) {
+			continue
+		}
+		// the earliest, because the shifted time may be after the next time of the wall clock
+		var next time.Time
+		for h := 0; h < 24; h++ {
+			if s.hour&(1<<uint(h)) == 0 {
+				continue
+			}
+			for m := 0; m < 60; m++ {
+				if s.minute&(1<<uint(m)) == 0 {
+					continue
+				}
+				c := wallClock(day.Year(), day.Month(), day.Day(), h, m, s.loc)
+				if c.After(t) && (next.IsZero() || c.Before(next)) {
+					next = c
+				}
+			}
+		}
+		if !next.IsZero() {
+			return next
+		}
+	}
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	// either matches if both are restricted
+	return dom || dow
+}
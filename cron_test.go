@@ -0,0 +1,142 @@
+package rotate
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	t.Parallel()
+
+	utc := func(y int, m time.Month, d, h, min int) time.Time {
+		return time.Date(y, m, d, h, min, 0, 0, time.UTC)
+	}
+	// 2026-10-17 is Saturday
+	from := utc(2026, 10, 17, 10, 30)
+
+	tt := []struct {
+		expr string
+		want time.Time
+	}{
+		{expr: "* * * * *", want: utc(2026, 10, 17, 10, 31)},
+		{expr: "0 */6 * * *", want: utc(2026, 10, 17, 12, 0)},
+		{expr: "30 10 * * *", want: utc(2026, 10, 18, 10, 30)},
+		{expr: "15,45 * * * *", want: utc(2026, 10, 17, 10, 45)},
+		{expr: "0 9-17/4 * * *", want: utc(2026, 10, 17, 13, 0)},
+		{expr: "0 0 * * mon-fri", want: utc(2026, 10, 19, 0, 0)},
+		{expr: "0 0 * * 7", want: utc(2026, 10, 18, 0, 0)},
+		{expr: "0 0 1 * *", want: utc(2026, 11, 1, 0, 0)},
+		{expr: "0 0 1 jan *", want: utc(2027, 1, 1, 0, 0)},
+		{expr: "0 0 29 2 *", want: utc(2028, 2, 29, 0, 0)},
+		// either the day of month or the day of week
+		{expr: "0 0 20 * sun", want: utc(2026, 10, 18, 0, 0)},
+		{expr: "0 0 */10 * *", want: utc(2026, 10, 21, 0, 0)},
+		{expr: "@hourly", want: utc(2026, 10, 17, 11, 0)},
+		{expr: "@daily", want: utc(2026, 10, 18, 0, 0)},
+		{expr: "CRON_TZ=UTC 0 0 * * *", want: utc(2026, 10, 18, 0, 0)},
+		{expr: "TZ=Asia/Tokyo 0 0 * * *", want: utc(2026, 10, 17, 15, 0)},
+	}
+	for i, te := range tt {
+		t.Run(fmt.Sprintf("#%d", i), func(t *testing.T) {
+			s, err := ParseCron(te.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cs, ok := s.(*cronSchedule); ok && cs.loc == time.Local {
+				cs.loc = time.UTC
+			}
+			if got := s.Next(from); !got.Equal(te.want) {
+				t.Errorf("%s: got %v, want %v", te.expr, got, te.want)
+			}
+		})
+	}
+}
+
+func TestParseCron_DST(t *testing.T) {
+	t.Parallel()
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	date := func(y int, m time.Month, d, h, min int) time.Time {
+		return time.Date(y, m, d, h, min, 0, 0, ny)
+	}
+
+	tt := []struct {
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		// 02:00-03:00 does not exist on 2026-03-08
+		{
+			expr: "CRON_TZ=America/New_York 30 2 * * *",
+			from: date(2026, 3, 7, 12, 0),
+			want: []time.Time{date(2026, 3, 8, 3, 30), date(2026, 3, 9, 2, 30)},
+		},
+		{
+			expr: "CRON_TZ=America/New_York 0,30 2,3 * * *",
+			from: date(2026, 3, 8, 0, 0),
+			want: []time.Time{date(2026, 3, 8, 3, 0), date(2026, 3, 8, 3, 30), date(2026, 3, 9, 2, 0)},
+		},
+		// 01:00-02:00 occurs twice on 2026-11-01
+		{
+			expr: "CRON_TZ=America/New_York 30 1 * * *",
+			from: date(2026, 10, 31, 12, 0),
+			want: []time.Time{date(2026, 11, 1, 1, 30), date(2026, 11, 2, 1, 30)},
+		},
+	}
+	for _, te := range tt {
+		s, err := ParseCron(te.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from := te.from
+		for _, want := range te.want {
+			got := s.Next(from)
+			if !got.Equal(want) {
+				t.Errorf("%s: Next(%v) got %v, want %v", te.expr, from, got, want)
+			}
+			from = got
+		}
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"0 0 30 2 *",
+		"CRON_TZ=Nowhere/Nowhere 0 0 * * *",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("%q: want error", expr)
+		}
+	}
+}
+
+func TestNewWriter_InvalidCron(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	if _, err := NewWriter(string(dir), "test.log", WithCron("0 0 30 2 *")); err == nil {
+		t.Error("want error")
+	}
+	if err := dir.waitFileNotCreated(time.Millisecond, "test.log"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,79 @@
+package rotate
+
+import (
+	"errors"
+	"os"
+)
+
+// Sentinel errors of the operations of the RotateError, for errors.Is.
+// e.g. errors.Is(err, rotate.ErrRename)
+var (
+	ErrStat     = errors.New("rotate: stat")
+	ErrOpen     = errors.New("rotate: open")
+	ErrRead     = errors.New("rotate: read")
+	ErrWrite    = errors.New("rotate: write")
+	ErrSync     = errors.New("rotate: sync")
+	ErrClose    = errors.New("rotate: close")
+	ErrRemove   = errors.New("rotate: remove")
+	ErrRename   = errors.New("rotate: rename")
+	ErrCopy     = errors.New("rotate: copy")
+	ErrTruncate = errors.New("rotate: truncate")
+	ErrMkdir    = errors.New("rotate: mkdir")
+	ErrGlob     = errors.New("rotate: glob")
+	ErrCompress = errors.New("rotate: compress")
+	ErrHook     = errors.New("rotate: hook")
+	ErrCommand  = errors.New("rotate: command")
+)
+
+var opErrors = map[string]error{
+	"stat":     ErrStat,
+	"open":     ErrOpen,
+	"read":     ErrRead,
+	"write":    ErrWrite,
+	"sync":     ErrSync,
+	"close":    ErrClose,
+	"remove":   ErrRemove,
+	"rename":   ErrRename,
+	"copy":     ErrCopy,
+	"truncate": ErrTruncate,
+	"mkdir":    ErrMkdir,
+	"glob":     ErrGlob,
+	"compress": ErrCompress,
+	"hook":     ErrHook,
+	"command":  ErrCommand,
+}
+
+// RotateError records an error of the rotation, and the operation and the file path that caused it
+type RotateError struct {
+	// Op is the operation. e.g. "rename"
+	Op string
+	// Path is the file path
+	Path string
+	// Err is the underlying error
+	Err error
+}
+
+func (e *RotateError) Error() string {
+	return "rotate: " + e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+// Is reports whether the target is the sentinel error of the operation
+func (e *RotateError) Is(target error) bool {
+	return opErrors[e.Op] == target
+}
+
+// Unwrap returns the underlying error
+func (e *RotateError) Unwrap() error {
+	return e.Err
+}
+
+// newRotateError returns the RotateError. The os.PathError and the os.LinkError are unwrapped not to duplicate the op and the path
+func newRotateError(op, path string, err error) *RotateError {
+	switch e := err.(type) {
+	case *os.PathError:
+		err = e.Err
+	case *os.LinkError:
+		err = e.Err
+	}
+	return &RotateError{Op: op, Path: path, Err: err}
+}
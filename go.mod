@@ -7,3 +7,5 @@ require (
 	github.com/mitchellh/go-ps v1.0.0
 	golang.org/x/sync v0.4.0
 )
+
+require github.com/klauspost/compress v1.18.0
@@ -0,0 +1,19 @@
+package rotate
+
+import "time"
+
+// RotateEvent describes a rotation of the current file, passed to the rotation hooks
+type RotateEvent struct {
+	// Path is the path of the current file
+	Path string
+	// RotatedPath is the path to which the current file was rotated.
+	// It is empty in the BeforeRotate hook, and if the current file was removed because the keeps is 0
+	RotatedPath string
+	// Size is the final size of the rotated file. In the BeforeRotate hook it is the size at the time
+	Size int64
+	// OpenedAt is the time when the current file was opened
+	OpenedAt time.Time
+	// ClosedAt is the time when the current file was closed, or truncated in the copy-truncate mode.
+	// It is zero in the BeforeRotate hook
+	ClosedAt time.Time
+}
@@ -10,3 +10,8 @@ import (
 func OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
 	return filesharedelete.OpenFile(name, flag, perm)
 }
+
+// SyncDir does nothing, because the directory can not be synced on windows
+func SyncDir(name string) error {
+	return nil
+}
@@ -15,13 +15,20 @@ type State struct {
 	// openedAt Unix time
 	openedAt int64
 	// file size (when opened) + written bytes
-	size  int64
-	state uint32
+	size int64
+	// file lines (when opened) + written lines
+	lines int64
+	// number of the Write calls
+	writes int64
+	// Unix time in nanoseconds of the first and the last write, 0 if not written
+	firstWriteAt int64
+	lastWriteAt  int64
+	state        uint32
 }
 
 // NewState creates a State
-func NewState(openedAt int64, size int64) *State {
-	return &State{openedAt: openedAt, size: size}
+func NewState(openedAt int64, size int64, lines int64) *State {
+	return &State{openedAt: openedAt, size: size, lines: lines}
 }
 
 // OpenedAt returns openedAt
@@ -34,9 +41,38 @@ func (s *State) Size() int64 {
 	return atomic.LoadInt64(&s.size)
 }
 
-// AddSize atomically
-func (s *State) AddSize(value int64) {
-	atomic.AddInt64(&s.size, value)
+// Lines returns `file lines (when opened) + written lines`
+func (s *State) Lines() int64 {
+	return atomic.LoadInt64(&s.lines)
+}
+
+// Writes returns the number of the Write calls
+func (s *State) Writes() int64 {
+	return atomic.LoadInt64(&s.writes)
+}
+
+// FirstWriteAt returns the Unix time in nanoseconds of the first write, 0 if not written
+func (s *State) FirstWriteAt() int64 {
+	return atomic.LoadInt64(&s.firstWriteAt)
+}
+
+// LastWriteAt returns the Unix time in nanoseconds of the last write, 0 if not written
+func (s *State) LastWriteAt() int64 {
+	return atomic.LoadInt64(&s.lastWriteAt)
+}
+
+// AddWrite records a Write call of the size and the lines at the Unix time in nanoseconds atomically
+func (s *State) AddWrite(size, lines, at int64) {
+	atomic.AddInt64(&s.size, size)
+	atomic.AddInt64(&s.lines, lines)
+	atomic.AddInt64(&s.writes, 1)
+	atomic.CompareAndSwapInt64(&s.firstWriteAt, 0, at)
+	for {
+		last := atomic.LoadInt64(&s.lastWriteAt)
+		if last >= at || atomic.CompareAndSwapInt64(&s.lastWriteAt, last, at) {
+			return
+		}
+	}
 }
 
 // CompareAndSwapAsRotating cas not-rotating to rotating
@@ -0,0 +1,239 @@
+package rotate
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Namer names the current and the rotated files
+type Namer interface {
+	// CurrentName returns the path of the file to write.
+	// base is the path joined the dir and the filename passed to NewWriter
+	CurrentName(base string) string
+	// RotatedName returns the path to which the current file is renamed.
+	// index is the 1-based position of the rotated file counted from the newest, t is the rotation time
+	RotatedName(current string, index int, t time.Time) string
+	// Glob returns the glob pattern which matches the rotated files of the current file
+	Glob(current string) string
+	// Parse parses the path of a rotated file and returns its order key. Newer files have larger keys.
+	// ok is false if the path is not a rotated file of the current file
+	Parse(current, path string) (key int64, ok bool)
+	// Shift reports whether the kept rotated files are renamed to the name of their new index on every rotation.
+	// If false, the Namer can implement Sequencer to make the name unique when it is already used
+	Shift() bool
+}
+
+// NumberNamer is a Namer that names the rotated files with the sequence number.
+// e.g. test.log > test.log.1 > test.log.2 ...
+type NumberNamer struct{}
+
+// CurrentName implements Namer
+func (NumberNamer) CurrentName(base string) string {
+	return base
+}
+
+// RotatedName implements Namer
+func (NumberNamer) RotatedName(current string, index int, _ time.Time) string {
+	return fmt.Sprintf("%s.%d", current, index)
+}
+
+// Glob implements Namer
+func (NumberNamer) Glob(current string) string {
+	return escapeGlob(current) + ".*"
+}
+
+// Parse implements Namer
+func (NumberNamer) Parse(current, path string) (int64, bool) {
+	s := strings.TrimPrefix(path, current+".")
+	if s == path {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 || strconv.Itoa(n) != s {
+		return 0, false
+	}
+	return -int64(n), true
+}
+
+// Shift implements Namer
+func (NumberNamer) Shift() bool {
+	return true
+}
+
+// TimeNamer is a Namer that names the rotated files with the rotation time.
+// The rotated files are never renamed after the rotation.
+// e.g. test.log > test.log.2026-10-17T10-00-00
+//
+// If the name is already used, e.g. by another rotation within the same second of the layout, the sequence number is appended.
+// e.g. test.log.2026-10-17T10-00-00.1.
+// If the formatted time is ambiguous in the Location, e.g. in the DST fall-back hour, the zone offset is appended.
+// e.g. test.log.2026-11-01T01-30-00-0500
+type TimeNamer struct {
+	// Layout formats the rotation time into the rotated file name. e.g. "2006-01-02T15-04-05"
+	Layout string
+	// DirLayout formats the rotation time into the name of the subdirectory in which the rotated files are placed.
+	// e.g. "2006-01-02" places test.log.15-04-05 in the 2026-10-17 directory. Empty means the same directory as the current file
+	DirLayout string
+	// KeepExt places the formatted time before the extension with the "-" separator.
+	// e.g. app.log > app-20261017-100000.log
+	KeepExt bool
+	// Location is the time zone in which the time is formatted and parsed. nil means time.Local
+	Location *time.Location
+}
+
+// zoneLayout is appended to the layout if the formatted time is ambiguous
+const zoneLayout = "-0700"
+
+// CurrentName implements Namer
+func (n TimeNamer) CurrentName(base string) string {
+	return base
+}
+
+// RotatedName implements Namer
+func (n TimeNamer) RotatedName(current string, _ int, t time.Time) string {
+	t = t.In(n.location())
+	dir, name := filepath.Split(current)
+	if n.DirLayout != "" {
+		dir = filepath.Join(dir, t.Format(n.DirLayout))
+	}
+	layout := n.Layout
+	if ambiguous(t) {
+		layout += zoneLayout
+	}
+	prefix, suffix := n.affixes(name)
+	return filepath.Join(dir, prefix+t.Format(layout)+suffix)
+}
+
+// ambiguous reports whether the wall clock of the t occurs twice in its location, e.g. in the DST fall-back hour
+func ambiguous(t time.Time) bool {
+	_, offset := t.Zone()
+	start, end := t.ZoneBounds()
+	if !start.IsZero() {
+		_, prev := start.Add(-time.Nanosecond).Zone()
+		if prev > offset && t.Sub(start) < time.Duration(prev-offset)*time.Second {
+			return true
+		}
+	}
+	if !end.IsZero() {
+		_, next := end.Zone()
+		if next < offset && end.Sub(t) <= time.Duration(offset-next)*time.Second {
+			return true
+		}
+	}
+	return false
+}
+
+// Sequenced implements Sequencer. The sequence number is placed before the extension if KeepExt is set
+func (n TimeNamer) Sequenced(current, rotated string, seq int) string {
+	_, suffix := n.affixes(filepath.Base(current))
+	return strings.TrimSuffix(rotated, suffix) + "." + strconv.Itoa(seq) + suffix
+}
+
+// Glob implements Namer
+func (n TimeNamer) Glob(current string) string {
+	dir, name := filepath.Split(current)
+	pattern := escapeGlob(filepath.Clean(dir))
+	if n.DirLayout != "" {
+		for range strings.Split(filepath.ToSlash(n.DirLayout), "/") {
+			pattern = filepath.Join(pattern, "*")
+		}
+	}
+	prefix, suffix := n.affixes(name)
+	return filepath.Join(pattern, escapeGlob(prefix)+"*"+escapeGlob(suffix))
+}
+
+// Parse implements Namer
+func (n TimeNamer) Parse(current, path string) (int64, bool) {
+	dir, name := filepath.Split(current)
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return 0, false
+	}
+	rel = filepath.ToSlash(rel)
+	layout := n.Layout
+	if n.DirLayout != "" {
+		layout = filepath.ToSlash(n.DirLayout) + "/" + layout
+	}
+	prefix, suffix := n.affixes(name)
+	i := strings.LastIndex(rel, "/") + 1
+	if !strings.HasPrefix(rel[i:], prefix) || !strings.HasSuffix(rel[i:], suffix) || len(rel[i:]) < len(prefix)+len(suffix) {
+		return 0, false
+	}
+	value := rel[:i] + strings.TrimSuffix(strings.TrimPrefix(rel[i:], prefix), suffix)
+	if t, ok := n.parseTime(layout, value); ok {
+		return t.UnixNano(), true
+	}
+	// e.g. 2026-10-17T10-00-00.1
+	j := strings.LastIndex(value, ".")
+	if j < 0 {
+		return 0, false
+	}
+	seq, err := strconv.Atoi(value[j+1:])
+	if err != nil || seq <= 0 || strconv.Itoa(seq) != value[j+1:] {
+		return 0, false
+	}
+	t, ok := n.parseTime(layout, value[:j])
+	if !ok {
+		return 0, false
+	}
+	return t.UnixNano() + int64(seq), true
+}
+
+func (n TimeNamer) parseTime(layout, value string) (time.Time, bool) {
+	for _, l := range []string{layout, layout + zoneLayout} {
+		// time.Parse accepts the fractional second not in the layout, e.g. the sequence number of 10-00-00.1
+		if t, err := time.ParseInLocation(l, value, n.location()); err == nil && t.Format(l) == value {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (n TimeNamer) location() *time.Location {
+	if n.Location == nil {
+		return time.Local
+	}
+	return n.Location
+}
+
+// Shift implements Namer
+func (n TimeNamer) Shift() bool {
+	return false
+}
+
+func (n TimeNamer) affixes(name string) (prefix, suffix string) {
+	if !n.KeepExt {
+		return name + ".", ""
+	}
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-", ext
+}
+
+// Sequencer is an optional interface of the Namer whose Shift is false, which makes the rotated name unique with the sequence number.
+// If the name returned by RotatedName is already used, e.g. by another rotation within the same day of the name,
+// the Writer renames the current file to the name returned by Sequenced instead.
+// Parse should return the key of the rotated name plus the seq for the sequenced name, so that the newer file has the larger key
+type Sequencer interface {
+	// Sequenced returns the rotated name with the 1-based sequence number seq, e.g. app-20261017.log > app-20261017-001.log
+	Sequenced(current, rotated string, seq int) string
+}
+
+func escapeGlob(s string) string {
+	if runtime.GOOS == "windows" {
+		// filepath.Match does not support escaping on windows
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,161 @@
+package rotate
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNamer_RotatedNameAndParse(t *testing.T) {
+	t.Parallel()
+
+	current := filepath.Join("logs", "app.log")
+	t1 := time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local)
+	t2 := t1.Add(time.Hour)
+
+	tt := []struct {
+		namer    Namer
+		wantName string
+	}{
+		{
+			namer:    NumberNamer{},
+			wantName: filepath.Join("logs", "app.log.1"),
+		},
+		{
+			namer:    TimeNamer{Layout: "20060102T150405"},
+			wantName: filepath.Join("logs", "app.log.20261017T100000"),
+		},
+		{
+			namer:    TimeNamer{Layout: "20060102-150405", KeepExt: true},
+			wantName: filepath.Join("logs", "app-20261017-100000.log"),
+		},
+		{
+			namer:    TimeNamer{Layout: "150405", DirLayout: "2006/01-02"},
+			wantName: filepath.Join("logs", "2026", "10-17", "app.log.100000"),
+		},
+	}
+	for _, te := range tt {
+		name := te.namer.RotatedName(current, 1, t1)
+		if name != te.wantName {
+			t.Errorf("RotatedName got %v, want %v", name, te.wantName)
+		}
+		if ok, _ := filepath.Match(te.namer.Glob(current), name); !ok {
+			t.Errorf("Glob %v does not match %v", te.namer.Glob(current), name)
+		}
+		older, ok := te.namer.Parse(current, te.namer.RotatedName(current, 2, t1))
+		if !ok {
+			t.Errorf("%T: Parse older failed", te.namer)
+		}
+		newer, ok := te.namer.Parse(current, te.namer.RotatedName(current, 1, t2))
+		if !ok {
+			t.Errorf("%T: Parse newer failed", te.namer)
+		}
+		if older >= newer {
+			t.Errorf("%T: want older key < newer key, got %v >= %v", te.namer, older, newer)
+		}
+		if _, ok := te.namer.Parse(current, current); ok {
+			t.Errorf("%T: Parse(current) reports ok", te.namer)
+		}
+		if _, ok := te.namer.Parse(current, filepath.Join("logs", "app.log.foo")); ok {
+			t.Errorf("%T: Parse(app.log.foo) reports ok", te.namer)
+		}
+	}
+}
+
+func TestTimeNamer_Sequenced(t *testing.T) {
+	t.Parallel()
+
+	current := filepath.Join("logs", "app.log")
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local)
+
+	tt := []struct {
+		namer    TimeNamer
+		wantName string
+	}{
+		{
+			namer:    TimeNamer{Layout: "20060102T150405"},
+			wantName: filepath.Join("logs", "app.log.20261017T100000.2"),
+		},
+		{
+			namer:    TimeNamer{Layout: "20060102-150405", KeepExt: true},
+			wantName: filepath.Join("logs", "app-20261017-100000.2.log"),
+		},
+	}
+	for _, te := range tt {
+		base := te.namer.RotatedName(current, 1, now)
+		if name := te.namer.Sequenced(current, base, 2); name != te.wantName {
+			t.Errorf("Sequenced got %v, want %v", name, te.wantName)
+		}
+		if ok, _ := filepath.Match(te.namer.Glob(current), te.wantName); !ok {
+			t.Errorf("Glob %v does not match %v", te.namer.Glob(current), te.wantName)
+		}
+		// older < base < .1 < .2 < newer
+		names := []string{
+			te.namer.RotatedName(current, 1, now.Add(-time.Second)),
+			base,
+			te.namer.Sequenced(current, base, 1),
+			te.namer.Sequenced(current, base, 2),
+			te.namer.RotatedName(current, 1, now.Add(time.Second)),
+		}
+		var last int64
+		for i, name := range names {
+			key, ok := te.namer.Parse(current, name)
+			if !ok {
+				t.Fatalf("Parse(%v) failed", name)
+			}
+			if i > 0 && key <= last {
+				t.Errorf("want the key of %v > the key of %v", name, names[i-1])
+			}
+			last = key
+		}
+	}
+}
+
+func TestTimeNamer_Location(t *testing.T) {
+	t.Parallel()
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip(err)
+	}
+	current := filepath.Join("logs", "app.log")
+	const layout = "2006-01-02T15-04-05"
+
+	// formatted and parsed in the Location, regardless of the zone of the rotation time
+	n := TimeNamer{Layout: layout, Location: time.UTC}
+	at := time.Date(2026, 10, 17, 10, 0, 0, 0, tokyo)
+	name := n.RotatedName(current, 1, at)
+	if want := filepath.Join("logs", "app.log.2026-10-17T01-00-00"); name != want {
+		t.Errorf("RotatedName got %v, want %v", name, want)
+	}
+	if key, ok := n.Parse(current, name); !ok || key != at.UnixNano() {
+		t.Errorf("Parse got %v %v, want %v", key, ok, at.UnixNano())
+	}
+
+	// the DST fall-back hour, 01:00-02:00 occurs twice on 2026-11-01
+	n = TimeNamer{Layout: layout, Location: ny}
+	start := time.Date(2026, 11, 1, 0, 30, 0, 0, ny)
+	var last int64
+	for i, d := range []time.Duration{0, 80 * time.Minute, 100 * time.Minute, 130 * time.Minute, 180 * time.Minute} {
+		at := start.Add(d)
+		name := n.RotatedName(current, 1, at)
+		key, ok := n.Parse(current, name)
+		if !ok {
+			t.Fatalf("Parse(%v) failed", name)
+		}
+		if key != at.UnixNano() {
+			t.Errorf("Parse(%v) got %v, want %v", name, time.Unix(0, key), at)
+		}
+		if i > 0 && key <= last {
+			t.Errorf("want the key of %v > the previous key", name)
+		}
+		last = key
+	}
+	if name := n.RotatedName(current, 1, start.Add(100*time.Minute)); name != filepath.Join("logs", "app.log.2026-11-01T01-10-00-0500") {
+		t.Errorf("RotatedName got %v", name)
+	}
+}
@@ -2,12 +2,45 @@ package rotate
 
 import (
 	"os"
+	"time"
 )
 
 type option struct {
 	permission os.FileMode
 	keeps      int
 	policy     PolicyFunc
+	namer      Namer
+	compressor Compressor
+	// number of the newest rotated files which are left uncompressed
+	compressDelay  int
+	maxAge         time.Duration
+	maxTotalSize   int64
+	watchInterval  time.Duration
+	copyTruncate   bool
+	timer          Schedule
+	now            func() time.Time
+	recordBoundary bool
+	// whether the timer is set by WithSchedule, which is cleared with the policy
+	scheduleTimer bool
+	// whether the lines of the existing file are counted on opening
+	countLines bool
+	// strict max size of the file, 0 means no limit
+	maxSize int64
+	// size of the size based policy, 0 if the policy is not the size based one
+	sizeLimit int64
+	// whether Write returns the error of the synchronous rotation
+	syncRotationError bool
+	errorHandler      func(error)
+	beforeRotate      func(RotateEvent) error
+	afterRotate       func(RotateEvent) error
+	onRotate          func(RotateEvent)
+	postRotate        Command
+	// size of the write buffer, 0 means no buffering
+	bufferSize    int
+	flushInterval time.Duration
+	syncPolicy    SyncPolicy
+	// err is the error of the invalid option, reported by NewWriter
+	err error
 }
 
 // OptionFunc let you change follow.Reader behavior.
@@ -24,6 +57,9 @@ func (o *option) apply(opts ...OptionFunc) {
 	o.permission = DefaultPermission
 	o.keeps = DefaultKeeps
 	o.policy = SizeBasedPolicy(DefaultSize)
+	o.sizeLimit = DefaultSize
+	o.namer = NumberNamer{}
+	o.now = time.Now
 	for _, fn := range opts {
 		fn(o)
 	}
@@ -46,13 +82,273 @@ func WithKeeps(v int) OptionFunc {
 // WithSizeBasedPolicy let you change the rotate policy
 func WithSizeBasedPolicy(size int64) OptionFunc {
 	return func(o *option) {
-		o.policy = SizeBasedPolicy(size)
+		o.setPolicy(SizeBasedPolicy(size), size)
+	}
+}
+
+// WithLineBasedPolicy let you change the rotate policy. It also counts the lines of the existing file like WithLineCount
+func WithLineBasedPolicy(n int64) OptionFunc {
+	return func(o *option) {
+		o.setPolicy(LineBasedPolicy(n), 0)
+		o.countLines = true
+	}
+}
+
+// WithLineCount let you count the lines of the existing file on opening, for the policies using FileState.Lines.
+// Otherwise FileState.Lines counts only the lines written by the Writer, so as not to read the whole existing file
+func WithLineCount() OptionFunc {
+	return func(o *option) {
+		o.countLines = true
+	}
+}
+
+// WithStrictSizeBasedPolicy let you change the rotate policy to the size based one, in the strict mode.
+// The Writer rotates synchronously before a Write call which would push the file over the size,
+// so that no file exceeds the size except the file of a single Write call larger than the size.
+// Write calls are serialized in this mode. With WithRecordBoundary, a record written by the multiple Write calls may exceed the size
+func WithStrictSizeBasedPolicy(size int64) OptionFunc {
+	return func(o *option) {
+		o.setPolicy(SizeBasedPolicy(size), size)
+		o.maxSize = size
 	}
 }
 
 // WithTimeBasedPolicy let you change the rotate policy
 func WithTimeBasedPolicy(fn func(openedAtUnix int64) bool) OptionFunc {
 	return func(o *option) {
-		o.policy = TimeBasedPolicy(fn)
+		o.setPolicy(TimeBasedPolicy(fn), 0)
+	}
+}
+
+// WithSchedule let you change the rotate policy to rotate at the time of the schedule, e.g. WithSchedule(Daily(0, 0, time.Local)).
+// The file is rotated at the time even when nothing is written, see WithRotationTimer
+func WithSchedule(s Schedule) OptionFunc {
+	return func(o *option) {
+		o.setPolicy(SchedulePolicy(s), 0)
+		o.timer = s
+		o.scheduleTimer = true
+	}
+}
+
+// WithRotationTimer let you evaluate the rotate policy at every time of the schedule in the background,
+// so that the time based policy rotates the file at the time even when nothing is written.
+// WithSchedule and WithCron set it implicitly, and the policy options following them clear it unless it is set by this option.
+// nil means no timer
+func WithRotationTimer(s Schedule) OptionFunc {
+	return func(o *option) {
+		o.timer = s
+		o.scheduleTimer = false
+	}
+}
+
+// WithCron let you change the rotate policy to rotate at the time of the standard 5-field cron expression, e.g. WithCron("0 */6 * * *").
+// NewWriter returns an error if the expression is invalid. See ParseCron for the syntax, and WithSchedule for the behavior
+func WithCron(expr string) OptionFunc {
+	return func(o *option) {
+		s, err := ParseCron(expr)
+		if err != nil {
+			o.err = err
+			return
+		}
+		WithSchedule(s)(o)
+	}
+}
+
+// WithPolicy let you change the rotate policy to an arbitrary one.
+// Use AnyPolicy, AllPolicy and NotPolicy to combine the policies, e.g.
+//
+//	WithPolicy(AllPolicy(
+//		AnyPolicy(SizeBasedPolicy(100*1024*1024), midnight),
+//		NotPolicy(openedWithinAMinute),
+//	))
+func WithPolicy(policy PolicyFunc) OptionFunc {
+	return func(o *option) {
+		o.setPolicy(policy, 0)
+	}
+}
+
+// WithTimeLayout let you name the rotated files with the rotation time formatted by the layout (e.g. "2006-01-02T15-04-05")
+// instead of the sequence number. The rotated files are never renamed after the rotation.
+// It is a shorthand for WithNamer(TimeNamer{Layout: layout})
+func WithTimeLayout(layout string) OptionFunc {
+	return WithNamer(TimeNamer{Layout: layout})
+}
+
+// WithNamer let you change the naming strategy of the rotated files
+func WithNamer(v Namer) OptionFunc {
+	return func(o *option) {
+		o.namer = v
+	}
+}
+
+// WithCompression let you compress the rotated files in the background.
+// e.g. WithCompression(Gzip) compresses test.log.1 into test.log.1.gz. nil means no compression
+func WithCompression(v Compressor) OptionFunc {
+	return func(o *option) {
+		o.compressor = v
+	}
+}
+
+// WithCompressDelay let you leave the newest n rotated files uncompressed, like the delaycompress of logrotate.
+// e.g. WithCompressDelay(1) leaves test.log.1 and compresses test.log.2 and older
+func WithCompressDelay(n int) OptionFunc {
+	return func(o *option) {
+		o.compressDelay = n
+	}
+}
+
+// WithMaxAge let you remove the rotated files modified more than d ago.
+// They are removed on every rotation and by the periodic sweep while the Writer is open. 0 means no limit
+func WithMaxAge(d time.Duration) OptionFunc {
+	return func(o *option) {
+		o.maxAge = d
+	}
+}
+
+// WithMaxTotalSize let you limit the total bytes of the current file and the rotated files including the compressed ones.
+// The oldest rotated files are removed until the total is within the size. 0 means no limit
+func WithMaxTotalSize(size int64) OptionFunc {
+	return func(o *option) {
+		o.maxTotalSize = size
+	}
+}
+
+// WithWatch let you check the current file every interval, and reopen the file path if the current file was moved or removed
+// by an operator or an external tool such as logrotate. 0 means no check
+func WithWatch(interval time.Duration) OptionFunc {
+	return func(o *option) {
+		o.watchInterval = interval
+	}
+}
+
+// WithCopyTruncate let you rotate by copying the current file to the rotated file and truncating the current file in place,
+// instead of renaming it. Use it when another process holds the current file open and cannot reopen it.
+// Note that the data written by another process between the copy and the truncation is lost
+func WithCopyTruncate() OptionFunc {
+	return func(o *option) {
+		o.copyTruncate = true
+	}
+}
+
+// WithNowFunc let you change the function which returns the current time,
+// used for FileState and the timestamps of the files. It is useful for testing policies
+func WithNowFunc(fn func() time.Time) OptionFunc {
+	return func(o *option) {
+		o.now = fn
+	}
+}
+
+// WithRecordBoundary let the Writer rotate synchronously within the Write call, and only after a Write call ending with a newline,
+// so that a record written by the multiple Write calls (e.g. by a buffered caller) never straddles two files.
+// With WithSizeBasedPolicy, it also rotates before a Write call starting a record which would push the file over the size.
+// Write calls are serialized in this mode
+func WithRecordBoundary() OptionFunc {
+	return func(o *option) {
+		o.recordBoundary = true
+	}
+}
+
+// WithSyncRotation let the Writer rotate synchronously within the Write call, instead of in the background.
+// If the rotation fails, Write returns the error as the *RotateError with the number of the written bytes,
+// and the rotation is retried on the next Write call. Write calls are serialized in this mode
+func WithSyncRotation() OptionFunc {
+	return func(o *option) {
+		o.syncRotationError = true
+	}
+}
+
+// WithErrorHandler let you receive every error of the background operations (e.g. the rotation in the background,
+// the compression, the sweep and the close of the files), instead of logging it through the logger package.
+// The errors of the file operations are the *RotateError, which matches the sentinel errors such as ErrRename by errors.Is.
+// The fn is called from the background goroutines, so it must be safe for concurrent use
+func WithErrorHandler(fn func(error)) OptionFunc {
+	return func(o *option) {
+		o.errorHandler = fn
+	}
+}
+
+// WithBuffer let you buffer the writes in memory up to the size bytes before writing them to the file,
+// and flush the buffer every the flushInterval. 0 flushInterval means no periodic flush.
+// The buffer is flushed to the current file before the rotation, and by Flush, Sync and Close.
+// The error of the flush by a Write call is reported to the error handler, and the data is kept for the next flush
+func WithBuffer(size int, flushInterval time.Duration) OptionFunc {
+	return func(o *option) {
+		o.bufferSize = size
+		o.flushInterval = flushInterval
+	}
+}
+
+// WithSyncPolicy let you change when the file is committed to the stable storage. The default is SyncNever
+func WithSyncPolicy(v SyncPolicy) OptionFunc {
+	return func(o *option) {
+		o.syncPolicy = v
+	}
+}
+
+// WithBeforeRotate let you run the fn before the current file is rotated.
+// The fn is called on the goroutine which performs the rotation (the caller of Write or Rotate in the synchronous mode,
+// otherwise a background goroutine) while holding the rotation lock, so the rotations never run the fn concurrently.
+// An error of the fn aborts the rotation including the cleanup of the rotated files, and the rotation is tried again later.
+// The error is reported as the *RotateError matching ErrHook
+func WithBeforeRotate(fn func(RotateEvent) error) OptionFunc {
+	return func(o *option) {
+		o.beforeRotate = fn
+	}
+}
+
+// WithAfterRotate let you run the fn after the current file is rotated and the next file is opened.
+// The fn is called on the same goroutine as the BeforeRotate hook while holding the rotation lock,
+// so the rotated file is never renamed, compressed or removed by the Writer until the fn returns.
+// Write calls are not blocked by the fn, unless the Writer rotates synchronously.
+// An error of the fn does not abort the compression and the cleanup of the rotated files,
+// and is reported to the error handler as the *RotateError matching ErrHook
+func WithAfterRotate(fn func(RotateEvent) error) OptionFunc {
+	return func(o *option) {
+		o.afterRotate = fn
+	}
+}
+
+// WithOnRotate let you be notified of the rotation without blocking it.
+// The fn is called on a new goroutine after each rotation, so it may run concurrently with the other hooks and Write calls.
+// The rotated file may be already renamed, compressed or removed by the Writer, use WithAfterRotate to handle the file itself.
+// Close waits for the fn to return
+func WithOnRotate(fn func(RotateEvent)) OptionFunc {
+	return func(o *option) {
+		o.onRotate = fn
+	}
+}
+
+// WithPostRotateCommand let you run the external command with the path of the rotated file after each rotation.
+// The command runs after the AfterRotate hook on the same goroutine, and the rotated file is never renamed, compressed
+// or removed by the Writer until the command exits. It is not run if the rotated file was removed because the keeps is 0.
+// The failure of the command is reported to the error handler as the *RotateError matching ErrCommand,
+// which wraps the *CommandError having the exit status and the stderr output
+func WithPostRotateCommand(cmd Command) OptionFunc {
+	return func(o *option) {
+		o.postRotate = cmd
+	}
+}
+
+// setPolicy replaces the rotate policy, and resets the settings of the previous policy.
+// sizeLimit is the size of the size based policy, 0 if the policy is not the size based one
+func (o *option) setPolicy(policy PolicyFunc, sizeLimit int64) {
+	o.policy = policy
+	o.sizeLimit = sizeLimit
+	o.maxSize = 0
+	if o.scheduleTimer {
+		o.timer = nil
+		o.scheduleTimer = false
+	}
+}
+
+// syncRotation reports whether the Writer rotates synchronously within the Write call
+func (o *option) syncRotation() bool {
+	return o.recordBoundary || o.maxSize > 0 || o.syncRotationError
+}
+
+func (o *option) compressionExt() string {
+	if o.compressor == nil {
+		return ""
 	}
+	return o.compressor.Extension()
 }
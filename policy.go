@@ -1,11 +1,25 @@
 package rotate
 
+import (
+	"time"
+)
+
 // FileState holds the state of the current write destination file
 type FileState struct {
-	// openedAt Unix time
+	// openedAt Unix time. The modification time if the existing file was opened
 	OpenedAt int64
 	// file size (when opened) + written bytes
 	Size int64
+	// file lines (when opened, if WithLineBasedPolicy or WithLineCount is set) + written lines, counted by the newline characters
+	Lines int64
+	// number of the Write calls since opened
+	Writes int64
+	// time of the first write since opened, zero if not written
+	FirstWriteAt time.Time
+	// time of the last write since opened, zero if not written
+	LastWriteAt time.Time
+	// current time, see WithNowFunc
+	Now time.Time
 }
 
 // PolicyFunc is a type of rotate policy function
@@ -23,9 +37,54 @@ func SizeBasedPolicy(size int64) PolicyFunc {
 	}
 }
 
+// LineBasedPolicy returns line based rotate policy, which rotates after n newline-terminated records
+func LineBasedPolicy(n int64) PolicyFunc {
+	return func(fileState FileState) bool {
+		return fileState.Lines >= n
+	}
+}
+
 // TimeBasedPolicy returns time based rotate policy
 func TimeBasedPolicy(fn func(openedAtUnix int64) bool) PolicyFunc {
 	return func(fileState FileState) bool {
 		return fn(fileState.OpenedAt)
 	}
 }
+
+// AnyPolicy returns the policy which reports the need of rotate if any of the policies reports it
+func AnyPolicy(policies ...PolicyFunc) PolicyFunc {
+	return func(fileState FileState) bool {
+		for _, p := range policies {
+			if p.NeedRotate(fileState) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AllPolicy returns the policy which reports the need of rotate if all of the policies report it
+func AllPolicy(policies ...PolicyFunc) PolicyFunc {
+	return func(fileState FileState) bool {
+		for _, p := range policies {
+			if !p.NeedRotate(fileState) {
+				return false
+			}
+		}
+		return len(policies) > 0
+	}
+}
+
+// NotPolicy returns the policy which reports the opposite of the policy
+func NotPolicy(policy PolicyFunc) PolicyFunc {
+	return func(fileState FileState) bool {
+		return !policy.NeedRotate(fileState)
+	}
+}
+
+func (f FileState) now() time.Time {
+	if f.Now.IsZero() {
+		return time.Now()
+	}
+	return f.Now
+}
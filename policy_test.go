@@ -0,0 +1,41 @@
+package rotate
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPolicyCombinators(t *testing.T) {
+	t.Parallel()
+
+	yes := PolicyFunc(func(FileState) bool { return true })
+	no := PolicyFunc(func(FileState) bool { return false })
+	size100 := SizeBasedPolicy(100)
+	opened10 := TimeBasedPolicy(func(openedAtUnix int64) bool { return openedAtUnix <= 10 })
+
+	tt := []struct {
+		policy PolicyFunc
+		state  FileState
+		want   bool
+	}{
+		{policy: AnyPolicy(), want: false},
+		{policy: AnyPolicy(no, no), want: false},
+		{policy: AnyPolicy(no, yes), want: true},
+		{policy: AllPolicy(), want: false},
+		{policy: AllPolicy(yes, no), want: false},
+		{policy: AllPolicy(yes, yes), want: true},
+		{policy: NotPolicy(yes), want: false},
+		{policy: NotPolicy(no), want: true},
+		{policy: AllPolicy(AnyPolicy(size100, opened10), NotPolicy(SizeBasedPolicy(200))), state: FileState{OpenedAt: 20, Size: 100}, want: true},
+		{policy: AllPolicy(AnyPolicy(size100, opened10), NotPolicy(SizeBasedPolicy(200))), state: FileState{OpenedAt: 10, Size: 0}, want: true},
+		{policy: AllPolicy(AnyPolicy(size100, opened10), NotPolicy(SizeBasedPolicy(200))), state: FileState{OpenedAt: 20, Size: 99}, want: false},
+		{policy: AllPolicy(AnyPolicy(size100, opened10), NotPolicy(SizeBasedPolicy(200))), state: FileState{OpenedAt: 10, Size: 200}, want: false},
+	}
+	for i, te := range tt {
+		t.Run(fmt.Sprintf("#%d", i), func(t *testing.T) {
+			if got := te.policy.NeedRotate(te.state); got != te.want {
+				t.Errorf("got %v, want %v", got, te.want)
+			}
+		})
+	}
+}
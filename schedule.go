@@ -0,0 +1,94 @@
+package rotate
+
+import (
+	"time"
+)
+
+// Schedule is a schedule of the rotation
+type Schedule interface {
+	// Next returns the next rotation time after t. The zero time means no next
+	Next(t time.Time) time.Time
+}
+
+// ScheduleFunc is an adapter to allow the use of ordinary functions as the Schedule
+type ScheduleFunc func(t time.Time) time.Time
+
+// Next implements Schedule
+func (f ScheduleFunc) Next(t time.Time) time.Time {
+	return f(t)
+}
+
+// SchedulePolicy returns the policy which reports the need of rotate when the next time of the schedule after the file was opened has come
+func SchedulePolicy(s Schedule) PolicyFunc {
+	return func(fileState FileState) bool {
+		next := s.Next(time.Unix(fileState.OpenedAt, 0))
+		return !next.IsZero() && !fileState.now().Before(next)
+	}
+}
+
+// Hourly returns the schedule at the top of every hour in the loc. nil loc means time.Local
+func Hourly(loc *time.Location) Schedule {
+	loc = locationOrLocal(loc)
+	return ScheduleFunc(func(t time.Time) time.Time {
+		// align the local hours to the absolute hours, so as to work with the half-hour offset zones and the DST transitions
+		_, offset := t.In(loc).Zone()
+		off := time.Duration(offset) * time.Second
+		return t.Add(off).Truncate(time.Hour).Add(-off).Add(time.Hour).In(loc)
+	})
+}
+
+// Daily returns the schedule at hour:min of every day in the loc. nil loc means time.Local.
+// If hour:min does not exist in the day because of the DST transition, it is shifted by the gap. e.g. 02:30 > 03:30
+func Daily(hour, min int, loc *time.Location) Schedule {
+	loc = locationOrLocal(loc)
+	return ScheduleFunc(func(t time.Time) time.Time {
+		t = t.In(loc)
+		for i := 0; ; i++ {
+			next := wallClock(t.Year(), t.Month(), t.Day()+i, hour, min, loc)
+			if next.After(t) {
+				return next
+			}
+		}
+	})
+}
+
+// Weekly returns the schedule at hour:min of the weekday of every week in the loc. nil loc means time.Local.
+// If hour:min does not exist in the day because of the DST transition, it is shifted by the gap. e.g. 02:30 > 03:30
+func Weekly(weekday time.Weekday, hour, min int, loc *time.Location) Schedule {
+	loc = locationOrLocal(loc)
+	return ScheduleFunc(func(t time.Time) time.Time {
+		t = t.In(loc)
+		for i := 0; ; i++ {
+			day := time.Date(t.Year(), t.Month(), t.Day()+i, 0, 0, 0, 0, loc)
+			if day.Weekday() != weekday {
+				continue
+			}
+			next := wallClock(day.Year(), day.Month(), day.Day(), hour, min, loc)
+			if next.After(t) {
+				return next
+			}
+		}
+	})
+}
+
+// wallClock returns the time of the wall clock in the loc.
+// If the wall clock is in the gap of the DST transition, it returns the time shifted by the gap
+func wallClock(year int, month time.Month, day, hour, min int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, hour, min, 0, 0, loc)
+	if t.Hour() == hour && t.Minute() == min {
+		return t
+	}
+	_, before := t.Zone()
+	_, after := t.Add(2 * time.Hour).Zone()
+	if after > before {
+		t = t.Add(time.Duration(after-before) * time.Second)
+	}
+	return t
+}
+
+func locationOrLocal(loc *time.Location) *time.Location {
+	if loc == nil {
+		return time.Local
+	}
+	return loc
+}
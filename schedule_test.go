@@ -0,0 +1,69 @@
+package rotate
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	t.Parallel()
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip(err)
+	}
+	at := func(loc *time.Location, y int, m time.Month, d, h, min int) time.Time {
+		return time.Date(y, m, d, h, min, 0, 0, loc)
+	}
+
+	tt := []struct {
+		schedule Schedule
+		t        time.Time
+		want     time.Time
+	}{
+		{schedule: Hourly(ny), t: at(ny, 2026, 10, 17, 10, 30), want: at(ny, 2026, 10, 17, 11, 0)},
+		{schedule: Hourly(ny), t: at(ny, 2026, 10, 17, 10, 0), want: at(ny, 2026, 10, 17, 11, 0)},
+		{schedule: Hourly(kolkata), t: at(kolkata, 2026, 10, 17, 10, 59), want: at(kolkata, 2026, 10, 17, 11, 0)},
+		// 2026-11-01 01:00 EDT -> 01:00 EST
+		{schedule: Hourly(ny), t: time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC), want: time.Date(2026, 11, 1, 6, 0, 0, 0, time.UTC)},
+		{schedule: Hourly(ny), t: time.Date(2026, 11, 1, 6, 0, 0, 0, time.UTC), want: time.Date(2026, 11, 1, 7, 0, 0, 0, time.UTC)},
+		{schedule: Daily(0, 0, ny), t: at(ny, 2026, 10, 17, 10, 30), want: at(ny, 2026, 10, 18, 0, 0)},
+		{schedule: Daily(12, 0, ny), t: at(ny, 2026, 10, 17, 10, 30), want: at(ny, 2026, 10, 17, 12, 0)},
+		{schedule: Daily(12, 0, ny), t: at(ny, 2026, 10, 17, 12, 0), want: at(ny, 2026, 10, 18, 12, 0)},
+		{schedule: Daily(0, 0, ny), t: at(ny, 2026, 12, 31, 23, 59), want: at(ny, 2027, 1, 1, 0, 0)},
+		// 2026-03-08 02:00 EST -> 03:00 EDT, 02:30 does not exist
+		{schedule: Daily(2, 30, ny), t: at(ny, 2026, 3, 7, 22, 0), want: at(ny, 2026, 3, 8, 3, 30)},
+		// 23 hours day
+		{schedule: Daily(0, 0, ny), t: at(ny, 2026, 3, 8, 0, 0), want: time.Date(2026, 3, 9, 4, 0, 0, 0, time.UTC)},
+		// 2026-10-17 is Saturday
+		{schedule: Weekly(time.Monday, 0, 0, ny), t: at(ny, 2026, 10, 17, 10, 30), want: at(ny, 2026, 10, 19, 0, 0)},
+		{schedule: Weekly(time.Saturday, 12, 0, ny), t: at(ny, 2026, 10, 17, 10, 30), want: at(ny, 2026, 10, 17, 12, 0)},
+		{schedule: Weekly(time.Saturday, 9, 0, ny), t: at(ny, 2026, 10, 17, 10, 30), want: at(ny, 2026, 10, 24, 9, 0)},
+	}
+	for i, te := range tt {
+		t.Run(fmt.Sprintf("#%d", i), func(t *testing.T) {
+			if got := te.schedule.Next(te.t); !got.Equal(te.want) {
+				t.Errorf("got %v, want %v", got, te.want)
+			}
+		})
+	}
+}
+
+func TestSchedulePolicy(t *testing.T) {
+	t.Parallel()
+
+	openedAt := time.Date(2026, 10, 17, 10, 59, 59, 0, time.Local)
+	p := SchedulePolicy(Hourly(time.Local))
+
+	if p.NeedRotate(FileState{OpenedAt: openedAt.Unix(), Now: openedAt}) {
+		t.Error("want not rotate before the boundary")
+	}
+	if !p.NeedRotate(FileState{OpenedAt: openedAt.Unix(), Now: openedAt.Add(time.Second)}) {
+		t.Error("want rotate at the boundary")
+	}
+}
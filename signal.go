@@ -0,0 +1,56 @@
+package rotate
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// SignalAction is an action of the Writer on receiving the signal
+type SignalAction int
+
+// SignalActions
+const (
+	// SignalRotate rotates the current file
+	SignalRotate SignalAction = iota
+	// SignalReopen reopens the file path, e.g. after an external logrotate moved the current file
+	SignalReopen
+)
+
+// HandleSignal lets the Writer do the action on receiving any of the sigs (e.g. syscall.SIGHUP),
+// until the returned stop function is called or the Writer is closed.
+// It does nothing if no sigs are given, unlike signal.Notify which relays all the incoming signals
+func (w *Writer) HandleSignal(action SignalAction, sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		return func() {}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	quit := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-quit:
+				return
+			case <-w.done:
+				return
+			case <-ch:
+				var err error
+				switch action {
+				case SignalRotate:
+					err = w.Rotate()
+				case SignalReopen:
+					err = w.Reopen()
+				}
+				if err != nil {
+					w.reportError(err)
+				}
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(quit) }) }
+}
@@ -0,0 +1,82 @@
+//go:build !windows
+// +build !windows
+
+package rotate
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWriter_HandleSignal(t *testing.T) {
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	w, err := NewWriter(string(dir), "test.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// rotate
+	stop := w.HandleSignal(SignalRotate, syscall.SIGUSR1)
+	if err := writeNCount(w, "a", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.waitFileCreated(time.Second, "test.log", "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	stop()
+
+	// reopen
+	stop = w.HandleSignal(SignalReopen, syscall.SIGUSR2)
+	defer stop()
+	if err := os.Rename(filepath.Join(string(dir), "test.log"), filepath.Join(string(dir), "moved.log")); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.waitFileCreated(time.Second, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeNCount(w, "b", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("a", 10, dir, "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("b", 10, dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_HandleSignal_NoSignals(t *testing.T) {
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	w, err := NewWriter(string(dir), "test.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	stop := w.HandleSignal(SignalRotate)
+	defer stop()
+	if err := writeNCount(w, "a", 10); err != nil {
+		t.Fatal(err)
+	}
+	// SIGURG is used by the Go runtime
+	if err := syscall.Kill(os.Getpid(), syscall.SIGURG); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.waitFileNotCreated(200*time.Millisecond, "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+}
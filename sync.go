@@ -0,0 +1,47 @@
+package rotate
+
+import "time"
+
+type syncMode int
+
+const (
+	syncNever syncMode = iota
+	syncOnRotate
+	syncEveryWrite
+	syncEveryBytes
+	syncEveryInterval
+)
+
+// SyncPolicy is a policy of committing the file to the stable storage (fsync).
+// Any policy but SyncNever also commits the current file on the rotation and on Close,
+// and the directory of the file after renaming it
+type SyncPolicy struct {
+	mode     syncMode
+	bytes    int64
+	interval time.Duration
+}
+
+// Built-in sync policies
+var (
+	// SyncNever leaves the commit to the OS
+	SyncNever = SyncPolicy{mode: syncNever}
+	// SyncOnRotate commits the file only on the rotation and on Close
+	SyncOnRotate = SyncPolicy{mode: syncOnRotate}
+	// SyncEveryWrite commits the file on every Write call
+	SyncEveryWrite = SyncPolicy{mode: syncEveryWrite}
+)
+
+// SyncEveryBytes returns the SyncPolicy which commits the file every time n bytes are written
+func SyncEveryBytes(n int64) SyncPolicy {
+	return SyncPolicy{mode: syncEveryBytes, bytes: n}
+}
+
+// SyncEveryInterval returns the SyncPolicy which commits the file every the interval in the background
+func SyncEveryInterval(interval time.Duration) SyncPolicy {
+	return SyncPolicy{mode: syncEveryInterval, interval: interval}
+}
+
+// syncs reports whether the policy commits the file at all
+func (p SyncPolicy) syncs() bool {
+	return p.mode != syncNever
+}
@@ -1,10 +1,16 @@
 package rotate
 
 import (
-	"fmt"
+	"bytes"
+	"context"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kei2100/rotate/internal/file"
@@ -12,26 +18,59 @@ import (
 	"github.com/kei2100/rotate/logger"
 )
 
+var newline = []byte{'\n'}
+
+// ErrClosed is returned when the Writer is already closed
+var ErrClosed = errors.New("rotate: writer is closed")
+
 // NewWriter creates a *rotate.Writer
 func NewWriter(dir, filename string, opts ...OptionFunc) (*Writer, error) {
 	var opt option
 	opt.apply(opts...)
+	if opt.err != nil {
+		return nil, opt.err
+	}
 
-	filePath := filepath.Join(dir, filename)
-	f, err := file.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, opt.permission)
+	filePath := opt.namer.CurrentName(filepath.Join(dir, filename))
+	f, fi, err := openAppend(filePath, opt.permission)
 	if err != nil {
 		return nil, err
 	}
-	fi, err := f.Stat()
+	lines, err := existingLines(filePath, fi, opt.countLines)
 	if err != nil {
+		f.Close()
 		return nil, err
 	}
-	return &Writer{
-		f:        f,
-		state:    state.NewState(time.Now().Unix(), fi.Size()),
-		filePath: filePath,
-		opt:      opt,
-	}, nil
+	w := &Writer{
+		f:          f,
+		state:      openedState(fi, lines, opt.now()),
+		filePath:   filePath,
+		opt:        opt,
+		done:       make(chan struct{}),
+		rotateSem:  make(chan struct{}, 1),
+		atBoundary: true,
+	}
+	if opt.maxAge > 0 {
+		w.wg.Add(1)
+		go w.sweep(sweepInterval(opt.maxAge))
+	}
+	if opt.watchInterval > 0 {
+		w.wg.Add(1)
+		go w.watch(opt.watchInterval)
+	}
+	if opt.timer != nil {
+		w.wg.Add(1)
+		go w.runTimer(opt.timer)
+	}
+	if opt.bufferSize > 0 && opt.flushInterval > 0 {
+		w.wg.Add(1)
+		go w.runEvery(opt.flushInterval, w.Flush)
+	}
+	if opt.syncPolicy.mode == syncEveryInterval && opt.syncPolicy.interval > 0 {
+		w.wg.Add(1)
+		go w.runEvery(opt.syncPolicy.interval, w.Sync)
+	}
+	return w, nil
 }
 
 // Writer is a rotating file writer
@@ -42,116 +81,1123 @@ type Writer struct {
 
 	filePath string
 	opt      option
+
+	// rotateSem serializes the operations on the rotated files
+	rotateSem chan struct{}
+	// compressMu serializes the compressions, which run without the rotation lock
+	compressMu sync.Mutex
+	wg         sync.WaitGroup
+	// done is closed when the Writer is closed
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// writeMu serializes the Write calls in the synchronous rotation mode
+	writeMu sync.Mutex
+	// atBoundary reports whether the last Write call ended with a newline. It is guarded by writeMu
+	atBoundary bool
+
+	// bufMu guards buf, the data written but not yet flushed to the file
+	bufMu sync.Mutex
+	buf   []byte
+	// unsynced is the bytes written since the last sync, for SyncEveryBytes
+	unsynced int64
 }
 
-// Write implements io.Writer
+// Write implements io.Writer.
+// Each Write call lands wholly in one file, because the file is never switched during a Write call
 func (w *Writer) Write(p []byte) (int, error) {
+	if w.opt.syncRotation() {
+		return w.writeSync(p)
+	}
+
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
-	n, err := w.f.Write(p)
+	n, err := w.write(p)
 	if err != nil {
 		return n, err
 	}
-	w.state.AddSize(int64(n))
-	if !w.opt.policy.NeedRotate(FileState{OpenedAt: w.state.OpenedAt(), Size: w.state.Size()}) {
+	w.state.AddWrite(int64(n), int64(bytes.Count(p[:n], newline)), w.opt.now().UnixNano())
+	w.rotateIfNeeded()
+	if err := w.syncAfterWrite(n); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+// writeSync writes the p and rotates the file synchronously if needed. Write calls are serialized.
+// If WithSyncRotation is set, it returns the error of the rotation as the *RotateError
+func (w *Writer) writeSync(p []byte) (int, error) {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+
+	if limit := w.preWriteLimit(); limit > 0 {
+		w.mu.RLock()
+		st := w.state
+		size := st.Size()
+		w.mu.RUnlock()
+		// rotate before the write pushes the file over the limit, unless the file is empty
+		if size > 0 && size+int64(len(p)) > limit {
+			if err := w.rotateState(context.Background(), st); err != nil && !errors.Is(err, ErrClosed) {
+				if w.opt.syncRotationError {
+					return 0, err
+				}
+				w.reportError(err)
+			}
+		}
+	}
+
+	w.mu.RLock()
+	n, err := w.write(p)
+	if err != nil {
+		w.mu.RUnlock()
+		return n, err
+	}
+	w.state.AddWrite(int64(n), int64(bytes.Count(p[:n], newline)), w.opt.now().UnixNano())
+	if err := w.syncAfterWrite(n); err != nil {
+		w.mu.RUnlock()
+		return n, err
+	}
+	st := w.state
+	need := w.opt.policy.NeedRotate(w.fileState())
+	w.mu.RUnlock()
+
+	if n > 0 {
+		w.atBoundary = p[n-1] == '\n'
+	}
+	if !need || (w.opt.recordBoundary && !w.atBoundary) {
 		return n, nil
 	}
+	if err := w.rotateState(context.Background(), st); err != nil && !errors.Is(err, ErrClosed) {
+		if w.opt.syncRotationError {
+			// the p has been written
+			return n, err
+		}
+		w.reportError(err)
+	}
+	return n, nil
+}
+
+// preWriteLimit returns the size over which writeSync rotates the file before the write, 0 means no rotation before the write.
+// The caller must hold writeMu
+func (w *Writer) preWriteLimit() int64 {
+	if w.opt.recordBoundary {
+		// never split the record, even in the strict mode
+		if !w.atBoundary {
+			return 0
+		}
+		return w.opt.sizeLimit
+	}
+	return w.opt.maxSize
+}
+
+// rotateIfNeeded starts the rotation in the background if the policy reports the need of rotate.
+// The caller must hold the read lock of w.mu
+func (w *Writer) rotateIfNeeded() {
+	if !w.opt.policy.NeedRotate(w.fileState()) {
+		return
+	}
 	if !w.state.CompareAndSwapAsRotating() {
-		return n, nil
+		return
 	}
 
-	go func(current *os.File, st *state.State, opt option) {
-		if err := pushAndShiftKeeps(w.filePath, opt.keeps); err != nil {
-			logger.Println(err)
-			logger.Println("rotate: wait for rotate until next writing")
-			st.CompareAndSwapAsNotRotating()
+	w.wg.Add(1)
+	go func(st *state.State) {
+		defer w.wg.Done()
+
+		w.lockRotation(context.Background()) // never fails with the background context
+		defer w.unlockRotation()
+
+		w.mu.RLock()
+		rotated := w.state != st
+		w.mu.RUnlock()
+		if rotated {
+			// already rotated by Rotate
 			return
 		}
-		next, err := file.OpenFile(w.filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, opt.permission)
-		if err != nil {
-			logger.Println(err)
-			logger.Println("rotate: wait for rotate until next writing")
+		if err := w.rotate(st); err != nil {
+			if errors.Is(err, ErrClosed) {
+				return
+			}
+			w.reportError(err)
 			st.CompareAndSwapAsNotRotating()
-			return
 		}
+	}(w.state)
+}
+
+// fileState returns the FileState of the current file. The caller must hold the read lock of w.mu
+func (w *Writer) fileState() FileState {
+	return FileState{
+		OpenedAt:     w.state.OpenedAt(),
+		Size:         w.state.Size(),
+		Lines:        w.state.Lines(),
+		Writes:       w.state.Writes(),
+		FirstWriteAt: unixNanoOrZero(w.state.FirstWriteAt()),
+		LastWriteAt:  unixNanoOrZero(w.state.LastWriteAt()),
+		Now:          w.opt.now(),
+	}
+}
 
-		w.mu.Lock()
-		defer w.mu.Unlock()
+func unixNanoOrZero(nsec int64) time.Time {
+	if nsec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nsec)
+}
 
-		if st.IsClosed() {
-			if err := next.Close(); err != nil {
-				logger.Printf("rotate: an error occurred while closing next file: %+v", err)
-			}
-			return
+// Rotate rotates the current file synchronously.
+// It is safe to call Rotate concurrently with Write and Close
+func (w *Writer) Rotate() error {
+	return w.RotateContext(context.Background())
+}
+
+// RotateContext is like Rotate but gives up waiting for another rotation in progress when the ctx is done
+func (w *Writer) RotateContext(ctx context.Context) error {
+	return w.rotateState(ctx, nil)
+}
+
+// rotateState rotates the current file synchronously if its state is the st, or regardless of the state if the st is nil
+func (w *Writer) rotateState(ctx context.Context, st *state.State) error {
+	if err := w.lockRotation(ctx); err != nil {
+		return err
+	}
+	defer w.unlockRotation()
+
+	w.mu.RLock()
+	current := w.state
+	w.mu.RUnlock()
+	if current.IsClosed() {
+		return ErrClosed
+	}
+	if st != nil && st != current {
+		// already rotated
+		return nil
+	}
+	// suppress the rotation by Write
+	current.CompareAndSwapAsRotating()
+	if err := w.rotate(current); err != nil {
+		current.CompareAndSwapAsNotRotating()
+		return err
+	}
+	return nil
+}
+
+// Reopen closes the current file and opens the file path again in the append mode.
+// Use it after an external tool such as logrotate moved or removed the current file
+func (w *Writer) Reopen() error {
+	w.lockRotation(context.Background()) // never fails with the background context
+	defer w.unlockRotation()
+
+	return w.reopen()
+}
+
+// reopen reopens the file path. The caller must hold the rotation lock.
+// The state is kept if the file path is still the current file, otherwise the lines of the file are counted before blocking writing
+func (w *Writer) reopen() error {
+	f, fi, err := openAppend(w.filePath, w.opt.permission)
+	if err != nil {
+		return err
+	}
+	w.mu.RLock()
+	current, err := w.f.Stat()
+	w.mu.RUnlock()
+	same := err == nil && os.SameFile(current, fi)
+	var lines int64
+	if !same {
+		if lines, err = existingLines(w.filePath, fi, w.opt.countLines); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.state.IsClosed() {
+		f.Close()
+		return ErrClosed
+	}
+	if err := w.flushAndSync(); err != nil {
+		w.reportError(err)
+		// not return
+	}
+	if err := w.f.Close(); err != nil {
+		w.reportError(newRotateError("close", w.filePath, err))
+		// not return
+	}
+	w.f = f
+	atomic.StoreInt64(&w.unsynced, 0)
+	if !same {
+		w.state = openedState(fi, lines, w.opt.now())
+	}
+	return nil
+}
+
+// rotate rotates the current file of the st and runs the hooks. The caller must hold the rotation lock
+func (w *Writer) rotate(st *state.State) error {
+	if st.IsClosed() {
+		return ErrClosed
+	}
+	ev := RotateEvent{Path: w.filePath, Size: st.Size(), OpenedAt: time.Unix(st.OpenedAt(), 0)}
+	if w.opt.beforeRotate != nil {
+		if err := w.opt.beforeRotate(ev); err != nil {
+			return &RotateError{Op: "hook", Path: w.filePath, Err: err}
+		}
+	}
+	var err error
+	if w.opt.copyTruncate {
+		ev, err = w.copyTruncate(st, ev)
+	} else {
+		ev, err = w.pushAndOpen(st, ev)
+	}
+	if err != nil {
+		return err
+	}
+	if w.opt.afterRotate != nil {
+		if err := w.opt.afterRotate(ev); err != nil {
+			w.reportError(&RotateError{Op: "hook", Path: ev.RotatedPath, Err: err})
 		}
-		if err := current.Close(); err != nil {
-			logger.Printf("rotate: an error occurred while closing current file: %+v", err)
-			// not return
+	}
+	if ev.RotatedPath != "" {
+		if err := w.opt.postRotate.run(ev.RotatedPath); err != nil {
+			w.reportError(err)
 		}
-		w.f = next
-		w.state = state.NewState(time.Now().Unix(), 0)
-	}(w.f, w.state, w.opt)
+	}
+	return nil
+}
 
-	return n, nil
+// pushAndOpen pushes the current file of the st to the rotated files and opens the next file.
+// The caller must hold the rotation lock
+func (w *Writer) pushAndOpen(st *state.State, ev RotateEvent) (RotateEvent, error) {
+	w.mu.RLock()
+	err := w.flushAndSync()
+	w.mu.RUnlock()
+	if err != nil {
+		return ev, err
+	}
+	rotated, err := pushAndShiftKeeps(w.filePath, w.opt, w.opt.now())
+	if err != nil {
+		return ev, err
+	}
+	next, err := file.OpenFile(w.filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, w.opt.permission)
+	if err != nil {
+		return ev, newRotateError("open", w.filePath, err)
+	}
+	w.syncDirs(rotated)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if st.IsClosed() {
+		if err := next.Close(); err != nil {
+			w.reportError(newRotateError("close", w.filePath, err))
+		}
+		return ev, ErrClosed
+	}
+	// the data buffered while renaming goes to the rotated file
+	if err := w.flushAndSync(); err != nil {
+		w.reportError(err)
+		// not return
+	}
+	if err := w.f.Close(); err != nil {
+		w.reportError(newRotateError("close", w.filePath, err))
+		// not return
+	}
+	ev.RotatedPath = rotated
+	ev.Size = st.Size()
+	ev.ClosedAt = w.opt.now()
+	w.f = next
+	atomic.StoreInt64(&w.unsynced, 0)
+	w.state = state.NewState(w.opt.now().Unix(), 0, 0)
+	w.compressInBackground()
+	w.notifyInBackground(ev)
+	return ev, nil
 }
 
-// Close closes the file and releases resources
-func (w *Writer) Close() error {
+// copyTruncate copies the current file of the st to the rotated files and truncates the current file in place.
+// It blocks writing while copying so as not to lose the data written by the Writer. The caller must hold the rotation lock
+func (w *Writer) copyTruncate(st *state.State, ev RotateEvent) (RotateEvent, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if st.IsClosed() {
+		return ev, ErrClosed
+	}
+	if err := w.flushBuffer(); err != nil {
+		return ev, err
+	}
+	rotated, err := pushAndShiftKeeps(w.filePath, w.opt, w.opt.now())
+	if err != nil {
+		return ev, err
+	}
+	w.syncDirs(rotated)
+	if err := truncateFile(w.filePath); err != nil {
+		return ev, err
+	}
+	atomic.StoreInt64(&w.unsynced, 0)
+	ev.RotatedPath = rotated
+	ev.Size = st.Size()
+	ev.ClosedAt = w.opt.now()
+	w.state = state.NewState(w.opt.now().Unix(), 0, 0)
+	w.compressInBackground()
+	w.notifyInBackground(ev)
+	return ev, nil
+}
+
+// compressInBackground starts the compression of the rotated files if needed. The caller must hold w.mu
+func (w *Writer) compressInBackground() {
+	if w.opt.compressor == nil {
+		return
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.compressRotated(w.opt)
+	}()
+}
+
+// notifyInBackground runs the OnRotate hook if set. The caller must hold w.mu
+func (w *Writer) notifyInBackground(ev RotateEvent) {
+	if w.opt.onRotate == nil {
+		return
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.opt.onRotate(ev)
+	}()
+}
+
+// openAppend opens the path in the append mode and returns the file and its info
+func openAppend(path string, perm os.FileMode) (*os.File, os.FileInfo, error) {
+	f, err := file.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, perm)
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, fi, nil
+}
+
+// existingLines returns the lines of the existing file if count is true, otherwise 0
+func existingLines(path string, fi os.FileInfo, count bool) (int64, error) {
+	if !count || fi.Size() == 0 {
+		return 0, nil
+	}
+	return countLines(path)
+}
+
+// openedState returns the state of the opened file.
+// The existing file is regarded as opened at its modification time, so that the time based policies rotate the stale file
+func openedState(fi os.FileInfo, lines int64, now time.Time) *state.State {
+	openedAt := now
+	if fi.Size() > 0 && fi.ModTime().Before(now) {
+		openedAt = fi.ModTime()
+	}
+	return state.NewState(openedAt.Unix(), fi.Size(), lines)
+}
+
+func countLines(path string) (int64, error) {
+	f, err := file.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return 0, newRotateError("open", path, err)
+	}
+	defer f.Close()
+
+	var lines int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		lines += int64(bytes.Count(buf[:n], newline))
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return 0, newRotateError("read", path, err)
+		}
+	}
+}
+
+// reportError reports the error of the background operation to the error handler, or the logger if not set
+func (w *Writer) reportError(err error) {
+	if w.opt.errorHandler != nil {
+		w.opt.errorHandler(err)
+		return
+	}
+	logger.Println(err)
+}
+
+// lockRotation acquires the lock which serializes the operations on the rotated files
+func (w *Writer) lockRotation(ctx context.Context) error {
+	select {
+	case w.rotateSem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *Writer) unlockRotation() {
+	<-w.rotateSem
+}
+
+// Flush writes the buffered data to the file
+func (w *Writer) Flush() error {
 	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.state.IsClosed() {
+		return ErrClosed
+	}
+	return w.flushBuffer()
+}
+
+// Sync writes the buffered data to the file and commits the file to the stable storage
+func (w *Writer) Sync() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.state.IsClosed() {
+		return ErrClosed
+	}
+	return w.syncFile()
+}
+
+// syncFile writes the buffered data to the file and commits the file to the stable storage. The caller must hold w.mu
+func (w *Writer) syncFile() error {
+	if err := w.flushBuffer(); err != nil {
+		return err
+	}
+	if err := w.f.Sync(); err != nil {
+		return newRotateError("sync", w.filePath, err)
+	}
+	return nil
+}
+
+// flushAndSync writes the buffered data to the file, and commits the file to the stable storage unless the sync policy is SyncNever.
+// The caller must hold w.mu
+func (w *Writer) flushAndSync() error {
+	if !w.opt.syncPolicy.syncs() {
+		return w.flushBuffer()
+	}
+	return w.syncFile()
+}
+
+// syncAfterWrite commits the file to the stable storage if the sync policy requires it after writing the n bytes.
+// The caller must hold w.mu
+func (w *Writer) syncAfterWrite(n int) error {
+	switch w.opt.syncPolicy.mode {
+	case syncEveryWrite:
+	case syncEveryBytes:
+		if atomic.AddInt64(&w.unsynced, int64(n)) < w.opt.syncPolicy.bytes {
+			return nil
+		}
+		atomic.StoreInt64(&w.unsynced, 0)
+	default:
+		return nil
+	}
+	return w.syncFile()
+}
+
+// syncDirs commits the directories of the current and the rotated file to the stable storage, if the sync policy requires it
+func (w *Writer) syncDirs(rotated string) {
+	if !w.opt.syncPolicy.syncs() {
+		return
+	}
+	dirs := []string{filepath.Dir(w.filePath)}
+	if rotated != "" && filepath.Dir(rotated) != dirs[0] {
+		dirs = append(dirs, filepath.Dir(rotated))
+	}
+	for _, d := range dirs {
+		if err := file.SyncDir(d); err != nil {
+			w.reportError(newRotateError("sync", d, err))
+		}
+	}
+}
+
+// write writes the p to the file, or to the buffer if WithBuffer is set. The caller must hold w.mu
+func (w *Writer) write(p []byte) (int, error) {
+	if w.opt.bufferSize <= 0 {
+		return w.f.Write(p)
+	}
+	if w.state.IsClosed() {
+		return 0, ErrClosed
+	}
+	w.bufMu.Lock()
+	defer w.bufMu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) < w.opt.bufferSize {
+		return len(p), nil
+	}
+	// the p has been accepted into the buffer, so the flush error is reported instead of returned
+	// not to make the caller write the p again. The unwritten data is retried by the next flush
+	if err := w.flushBufferLocked(); err != nil {
+		w.reportError(err)
+	}
+	return len(p), nil
+}
+
+// flushBuffer writes the buffered data to the file. The caller must hold w.mu
+func (w *Writer) flushBuffer() error {
+	w.bufMu.Lock()
+	defer w.bufMu.Unlock()
+
+	return w.flushBufferLocked()
+}
+
+// flushBufferLocked is flushBuffer with bufMu held. The unwritten data is left in the buffer on error
+func (w *Writer) flushBufferLocked() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	n, err := w.f.Write(w.buf)
+	w.buf = w.buf[:copy(w.buf, w.buf[n:])]
+	if err != nil {
+		return newRotateError("write", w.filePath, err)
+	}
+	return nil
+}
+
+// runEvery runs the fn every the interval until the Writer is closed
+func (w *Writer) runEvery(interval time.Duration, fn func() error) {
+	defer w.wg.Done()
+
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-tick.C:
+			if err := fn(); err != nil && !errors.Is(err, ErrClosed) {
+				w.reportError(err)
+			}
+		}
+	}
+}
+
+// Close flushes the buffered data, closes the file and releases resources.
+// It waits for the background compression and sweep of the rotated files to finish
+func (w *Writer) Close() error {
+	w.mu.Lock()
 	w.state.StoreAsClosed()
+	ferr := w.flushAndSync()
 	err := w.f.Close()
-	w.mu.RUnlock()
+	w.mu.Unlock()
+	w.closeOnce.Do(func() { close(w.done) })
 
+	w.wg.Wait()
+	if ferr != nil {
+		return ferr
+	}
 	return err
 }
 
-func formatRotatedPath(path string, num int) string {
-	return fmt.Sprintf("%s.%d", path, num)
+// sweep removes the expired rotated files periodically until the Writer is closed
+func (w *Writer) sweep(interval time.Duration) {
+	defer w.wg.Done()
+
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-tick.C:
+			w.removeExpiredRotated()
+		}
+	}
+}
+
+func (w *Writer) removeExpiredRotated() {
+	w.lockRotation(context.Background()) // never fails with the background context
+	defer w.unlockRotation()
+
+	files, err := listRotated(w.filePath, w.opt)
+	if err != nil {
+		w.reportError(err)
+		return
+	}
+	if _, err := removeExpired(files, w.opt.maxAge, w.opt.now()); err != nil {
+		w.reportError(err)
+	}
+}
+
+// watch reopens the file path periodically until the Writer is closed, if the current file was moved or removed
+func (w *Writer) watch(interval time.Duration) {
+	defer w.wg.Done()
+
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-tick.C:
+			w.reopenIfMoved()
+		}
+	}
+}
+
+func (w *Writer) reopenIfMoved() {
+	w.lockRotation(context.Background()) // never fails with the background context
+	defer w.unlockRotation()
+
+	w.mu.RLock()
+	if w.state.IsClosed() {
+		w.mu.RUnlock()
+		return
+	}
+	current, err := w.f.Stat()
+	w.mu.RUnlock()
+	if err != nil {
+		w.reportError(newRotateError("stat", w.filePath, err))
+		return
+	}
+	fi, err := os.Stat(w.filePath)
+	if err == nil && os.SameFile(current, fi) {
+		return
+	}
+	if err != nil && !os.IsNotExist(err) {
+		w.reportError(newRotateError("stat", w.filePath, err))
+		return
+	}
+	logger.Printf("rotate: %s was moved or removed, reopen it", w.filePath)
+	if err := w.reopen(); err != nil && !errors.Is(err, ErrClosed) {
+		w.reportError(err)
+	}
+}
+
+// maxTimerWait is the max duration of the timer wait, so as to follow the changes of the wall clock
+const maxTimerWait = time.Minute
+
+// runTimer evaluates the policy at every time of the schedule until the Writer is closed
+func (w *Writer) runTimer(s Schedule) {
+	defer w.wg.Done()
+
+	var last time.Time
+	for {
+		w.mu.RLock()
+		openedAt := time.Unix(w.state.OpenedAt(), 0)
+		w.mu.RUnlock()
+		if openedAt.After(last) {
+			last = openedAt
+		}
+		next := s.Next(last)
+		if next.IsZero() {
+			return
+		}
+
+		wait := time.Until(next)
+		if wait > maxTimerWait {
+			wait = maxTimerWait
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-w.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if time.Now().Before(next) {
+			continue
+		}
+
+		last = next
+		if w.opt.syncRotation() {
+			w.rotateOnTimerSync()
+			continue
+		}
+		w.mu.RLock()
+		if !w.state.IsClosed() {
+			w.rotateIfNeeded()
+		}
+		w.mu.RUnlock()
+	}
+}
+
+// rotateOnTimerSync rotates the file synchronously between the Write calls if needed
+func (w *Writer) rotateOnTimerSync() {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+
+	w.mu.RLock()
+	st := w.state
+	need := w.opt.policy.NeedRotate(w.fileState())
+	w.mu.RUnlock()
+	if !need || (w.opt.recordBoundary && !w.atBoundary) {
+		return
+	}
+	if err := w.rotateState(context.Background(), st); err != nil && !errors.Is(err, ErrClosed) {
+		w.reportError(err)
+	}
+}
+
+// sweepInterval returns a tenth of the maxAge, between 1 second and 1 hour
+func sweepInterval(maxAge time.Duration) time.Duration {
+	d := maxAge / 10
+	if d < time.Second {
+		return time.Second
+	}
+	if d > time.Hour {
+		return time.Hour
+	}
+	return d
+}
+
+// compressRotated compresses the uncompressed rotated files.
+// It holds the rotation lock only while listing and replacing the files, so as not to block the rotations while compressing
+func (w *Writer) compressRotated(opt option) {
+	w.compressMu.Lock()
+	defer w.compressMu.Unlock()
+
+	w.lockRotation(context.Background()) // never fails with the background context
+	files, err := listRotated(w.filePath, opt)
+	w.unlockRotation()
+	if err != nil {
+		w.reportError(err)
+		return
+	}
+	for i, f := range files {
+		if i < opt.compressDelay || f.ext != "" {
+			continue
+		}
+		if err := w.compressRotatedFile(f.path, opt); err != nil {
+			w.reportError(err)
+		}
+	}
+	if opt.maxTotalSize <= 0 {
+		return
+	}
+
+	w.lockRotation(context.Background()) // never fails with the background context
+	defer w.unlockRotation()
+
+	// the compression changes the total size
+	files, err = listRotated(w.filePath, opt)
+	if err != nil {
+		w.reportError(err)
+		return
+	}
+	w.mu.RLock()
+	currentSize := w.state.Size()
+	w.mu.RUnlock()
+	if _, err := removeOverTotalSize(files, opt.maxTotalSize, currentSize); err != nil {
+		w.reportError(err)
+	}
+}
+
+// compressRotatedFile compresses the path into the temporary file, and replaces the path with it under the rotation lock.
+// If the path was renamed or removed by the rotation meanwhile, the temporary file is discarded
+// and the file is compressed by the compression after the rotation
+func (w *Writer) compressRotatedFile(path string, opt option) error {
+	src, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return newRotateError("stat", path, err)
+	}
+	dst := path + opt.compressor.Extension()
+	tmp := dst + ".tmp"
+	if err := writeCompressed(path, tmp, opt.compressor, opt.permission); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	w.lockRotation(context.Background()) // never fails with the background context
+	defer w.unlockRotation()
+
+	if fi, err := os.Lstat(path); err != nil || !os.SameFile(src, fi) {
+		os.Remove(tmp)
+		return nil
+	}
+	return replaceCompressed(path, tmp, dst)
 }
 
-// e.g. path "log", keeps 3
+// e.g. path "log", keeps 3, NumberNamer
 // - log > log.1 | log.1 > log.2 | log.2 > log.3 | log.3 > remove
 // - log > log.1 | log.1 > log.2 |               | log.3 > noop
 // -             | log.1 > noop  | log.2 > noop  | log.3 > noop
-func pushAndShiftKeeps(path string, keeps int) error {
-	if _, err := os.Stat(path); err != nil {
+//
+// e.g. path "log", keeps 2, TimeNamer
+// - log > log.<now> | log.<t2> > noop | log.<t1> > remove
+//
+// It returns the path of the rotated file, or empty if the current file does not exist or was removed
+func pushAndShiftKeeps(path string, opt option, now time.Time) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			return "", nil
 		}
-		return fmt.Errorf("rotate: failed to get stat %s: %+v", path, err)
+		return "", newRotateError("stat", path, err)
 	}
+	keeps := opt.keeps
 	if keeps < 0 {
 		keeps = 0
 	}
-	files := make([]string, 0, keeps+1)
-	// - [log.3 log.2 log.1]
-	// - [log.3 log.1]
-	for i := keeps; i > 0; i-- {
-		p := formatRotatedPath(path, i)
-		if _, err := os.Stat(p); err != nil {
-			if os.IsNotExist(err) {
+	// - [log.1 log.2 log.3]
+	// - [log.1 log.3]
+	files, err := listRotated(path, opt)
+	if err != nil {
+		return "", err
+	}
+	rotated := opt.namer.RotatedName(path, 1, now)
+	if !opt.namer.Shift() {
+		// before the removal, so as not to reuse the name of the removed files
+		if rotated, err = uniqueRotatedName(path, rotated, files, opt); err != nil {
+			return "", err
+		}
+	}
+	files, err = removeExpired(files, opt.maxAge, now)
+	if err != nil {
+		return "", err
+	}
+	// the current file will be the newest rotated file
+	files, err = removeOverTotalSize(files, opt.maxTotalSize, fi.Size())
+	if err != nil {
+		return "", err
+	}
+	// keep keeps-1 files, so that the pushed file makes keeps files
+	n := keeps - 1
+	if n < 0 {
+		n = 0
+	}
+	for len(files) > n {
+		last := files[len(files)-1]
+		if err := os.Remove(last.path); err != nil && !os.IsNotExist(err) {
+			return "", newRotateError("remove", last.path, err)
+		}
+		files = files[:len(files)-1]
+	}
+	if keeps == 0 {
+		if opt.copyTruncate {
+			// the current file will be truncated
+			return "", nil
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return "", newRotateError("remove", path, err)
+		}
+		return "", nil
+	}
+	if opt.namer.Shift() {
+		for i := len(files) - 1; i >= 0; i-- {
+			nw := opt.namer.RotatedName(path, i+2, now) + files[i].ext
+			if err := renameFile(files[i].path, nw, opt.permission); err != nil {
+				return "", err
+			}
+		}
+	}
+	if opt.copyTruncate {
+		return rotated, copyFile(path, rotated, opt.permission, opt.syncPolicy.syncs())
+	}
+	return rotated, renameFile(path, rotated, opt.permission)
+}
+
+// uniqueRotatedName returns the rotated name which is not used by the files.
+// If the name is used, the namer which implements Sequencer appends the sequence number next to the largest one in use,
+// so that the newest file has the largest number
+func uniqueRotatedName(path, rotated string, files []rotatedFile, opt option) (string, error) {
+	names := make(map[string]bool, len(files))
+	for _, f := range files {
+		names[strings.TrimSuffix(f.path, f.ext)] = true
+	}
+	used := func(name string) bool {
+		return names[name] || exists(name) || exists(name+opt.compressionExt())
+	}
+	sq, ok := opt.namer.(Sequencer)
+	if !ok {
+		if used(rotated) {
+			return "", newRotateError("rename", rotated, os.ErrExist)
+		}
+		return rotated, nil
+	}
+	// the key of the sequenced name is the key of the name plus the sequence number
+	seq := 0
+	if key, ok := opt.namer.Parse(path, rotated); ok {
+		for _, f := range files {
+			k := f.key - key
+			if k > int64(seq) && strings.TrimSuffix(f.path, f.ext) == sq.Sequenced(path, rotated, int(k)) {
+				seq = int(k)
+			}
+		}
+	}
+	if seq == 0 && !used(rotated) {
+		return rotated, nil
+	}
+	for seq++; used(sq.Sequenced(path, rotated, seq)); seq++ {
+	}
+	return sq.Sequenced(path, rotated, seq), nil
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+type rotatedFile struct {
+	path    string
+	key     int64
+	modTime time.Time
+	size    int64
+	// extension of the compression, empty if not compressed
+	ext string
+}
+
+// listRotated returns the rotated files of the path, newest first
+func listRotated(path string, opt option) ([]rotatedFile, error) {
+	pattern := opt.namer.Glob(path)
+	ext := opt.compressionExt()
+	patterns := []string{pattern}
+	if ext != "" {
+		patterns = append(patterns, pattern+escapeGlob(ext))
+	}
+	seen := make(map[string]bool)
+	var files []rotatedFile
+	for _, pt := range patterns {
+		matches, err := filepath.Glob(pt)
+		if err != nil {
+			return nil, newRotateError("glob", pt, err)
+		}
+		for _, m := range matches {
+			if seen[m] {
+				continue
+			}
+			seen[m] = true
+			f := rotatedFile{path: m}
+			name := m
+			if ext != "" && strings.HasSuffix(m, ext) {
+				f.ext = ext
+				name = strings.TrimSuffix(m, ext)
+			}
+			key, ok := opt.namer.Parse(path, name)
+			if !ok {
 				continue
 			}
-			return fmt.Errorf("rotate: failed to get stat %s: %+v", p, err)
+			fi, err := os.Lstat(m)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, newRotateError("stat", m, err)
+			}
+			f.key = key
+			f.modTime = fi.ModTime()
+			f.size = fi.Size()
+			files = append(files, f)
 		}
-		files = append(files, p)
 	}
-	// - [log.3 log.2 log.1 log]
-	// - [log.3 log.1 log]
-	files = append(files, path)
-	if len(files) > keeps {
-		if err := os.Remove(files[0]); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("rotate: failed to remove %s", files[0])
+	sort.SliceStable(files, func(i, j int) bool { return files[i].key > files[j].key })
+	return files, nil
+}
+
+// removeExpired removes the rotated files modified more than maxAge ago and returns the rest.
+// maxAge <= 0 means no limit
+func removeExpired(files []rotatedFile, maxAge time.Duration, now time.Time) ([]rotatedFile, error) {
+	if maxAge <= 0 {
+		return files, nil
+	}
+	rest := make([]rotatedFile, 0, len(files))
+	for _, f := range files {
+		if now.Sub(f.modTime) <= maxAge {
+			rest = append(rest, f)
+			continue
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return nil, newRotateError("remove", f.path, err)
 		}
-		// [log.2 log.1 log]
-		files = files[1:]
 	}
-	for i, old := range files {
-		nw := formatRotatedPath(path, len(files)-i)
-		if err := os.Rename(old, nw); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("rotate: failed to rename %s to %s", old, nw)
+	return rest, nil
+}
+
+// removeOverTotalSize removes the oldest rotated files until the total size of them and the current file is within maxTotalSize,
+// and returns the rest. maxTotalSize <= 0 means no limit
+func removeOverTotalSize(files []rotatedFile, maxTotalSize, currentSize int64) ([]rotatedFile, error) {
+	if maxTotalSize <= 0 {
+		return files, nil
+	}
+	total := currentSize
+	for _, f := range files {
+		total += f.size
+	}
+	for len(files) > 0 && total > maxTotalSize {
+		last := files[len(files)-1]
+		if err := os.Remove(last.path); err != nil && !os.IsNotExist(err) {
+			return nil, newRotateError("remove", last.path, err)
 		}
+		total -= last.size
+		files = files[:len(files)-1]
+	}
+	return files, nil
+}
+
+func renameFile(old, nw string, perm os.FileMode) error {
+	if old == nw {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(nw), dirPermission(perm)); err != nil {
+		return newRotateError("mkdir", filepath.Dir(nw), err)
+	}
+	if err := os.Rename(old, nw); err != nil && !os.IsNotExist(err) {
+		return newRotateError("rename", old, err)
+	}
+	return nil
+}
+
+// truncateFile truncates the path through another write handle,
+// because the handle in the append mode can not truncate the file on windows
+func truncateFile(path string) error {
+	f, err := file.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return newRotateError("open", path, err)
+	}
+	if err := f.Truncate(0); err != nil {
+		f.Close()
+		return newRotateError("truncate", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return newRotateError("close", path, err)
 	}
 	return nil
 }
+
+// copyFile copies the src to the dst, and commits the dst to the stable storage if sync is true
+func copyFile(src, dst string, perm os.FileMode, sync bool) error {
+	if err := os.MkdirAll(filepath.Dir(dst), dirPermission(perm)); err != nil {
+		return newRotateError("mkdir", filepath.Dir(dst), err)
+	}
+	in, err := file.OpenFile(src, os.O_RDONLY, 0)
+	if err != nil {
+		return newRotateError("open", src, err)
+	}
+	defer in.Close()
+	out, err := file.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return newRotateError("open", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return newRotateError("copy", src, err)
+	}
+	if sync {
+		if err := out.Sync(); err != nil {
+			out.Close()
+			os.Remove(dst)
+			return newRotateError("sync", dst, err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		return newRotateError("close", dst, err)
+	}
+	return nil
+}
+
+// dirPermission adds the search permission to the readable classes of the file permission
+func dirPermission(perm os.FileMode) os.FileMode {
+	return perm | (perm&0444)>>2
+}
@@ -2,6 +2,8 @@ package rotate
 
 import (
 	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -9,7 +11,9 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -143,6 +147,1464 @@ func TestWriter_Rotate_Parallel(t *testing.T) {
 	}
 }
 
+func TestWriter_RotateExplicitly(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	w, err := NewWriter(string(dir), "test.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeNCount(w, "a", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("a", 10, dir, "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := emptyFile(dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeNCount(w, "b", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("b", 10, dir, "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("a", 10, dir, "test.log.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Rotate(); err != ErrClosed {
+		t.Errorf("want ErrClosed, got %v", err)
+	}
+}
+
+func TestWriter_RotateExplicitly_Parallel(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	const nGoroutines = 10
+	const keeps = nGoroutines * 3
+
+	w, err := NewWriter(string(dir), "test.log", WithKeeps(keeps), WithSizeBasedPolicy(50))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = nGroutinesDo(nGoroutines, func() error {
+		for i := 0; i < 2; i++ {
+			if err := writeNCount(w, "a", 10); err != nil {
+				return err
+			}
+			if err := w.Rotate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fis, err := ioutil.ReadDir(string(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filenames := make([]string, 0, len(fis))
+	for _, fi := range fis {
+		filenames = append(filenames, fi.Name())
+	}
+	if err := containsNCount("a", nGoroutines*2*10, dir, filenames...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_Reopen(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	w, err := NewWriter(string(dir), "test.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := writeNCount(w, "a", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(string(dir), "test.log"), filepath.Join(string(dir), "moved.log")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeNCount(w, "b", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("a", 10, dir, "moved.log"); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("b", 10, dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_Watch(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	w, err := NewWriter(string(dir), "test.log", WithWatch(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := writeNCount(w, "a", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(string(dir), "test.log"), filepath.Join(string(dir), "moved.log")); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.waitFileCreated(time.Second, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeNCount(w, "b", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("a", 10, dir, "moved.log"); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("b", 10, dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+
+	// removed
+	if err := os.Remove(filepath.Join(string(dir), "test.log")); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.waitFileCreated(time.Second, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_Rotate_CopyTruncate(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	const nBytes = 100
+	const keeps = 2
+
+	w, err := NewWriter(string(dir), "test.log", WithKeeps(keeps), WithSizeBasedPolicy(int64(nBytes)), WithCopyTruncate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	before, err := os.Stat(filepath.Join(string(dir), "test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeNCount(w, "a", nBytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.waitFileCreated(time.Second, "test.log", "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Rotate(); err != nil { // wait for the rotation by Write
+		t.Fatal(err)
+	}
+	if err := writeNCount(w, "b", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := os.Stat(filepath.Join(string(dir), "test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(before, after) {
+		t.Error("test.log is not the same file")
+	}
+	if err := containsNCount("a", nBytes, dir, "test.log.2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := emptyFile(dir, "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("b", 10, dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_RotationTimer(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	everySecond := ScheduleFunc(func(t time.Time) time.Time { return t.Truncate(time.Second).Add(time.Second) })
+	w, err := NewWriter(string(dir), "test.log", WithSchedule(everySecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := writeNCount(w, "a", 10); err != nil {
+		t.Fatal(err)
+	}
+	// rotate without writing
+	if err := dir.waitFileCreated(2500*time.Millisecond, "test.log", "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := retry(time.Second, 10*time.Millisecond, func() error {
+		rotated, err := filepath.Glob(filepath.Join(string(dir), "test.log.*"))
+		if err != nil {
+			return err
+		}
+		for i := range rotated {
+			rotated[i] = filepath.Base(rotated[i])
+		}
+		return containsNCount("a", 10, dir, rotated...)
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_RotationTimer_ReplacedPolicy(t *testing.T) {
+	t.Parallel()
+
+	daily := Daily(0, 0, time.Local)
+	tt := []struct {
+		name      string
+		opts      []OptionFunc
+		wantTimer bool
+	}{
+		{name: "schedule", opts: []OptionFunc{WithSchedule(daily)}, wantTimer: true},
+		{name: "schedule replaced", opts: []OptionFunc{WithSchedule(daily), WithSizeBasedPolicy(10)}},
+		{name: "cron replaced", opts: []OptionFunc{WithCron("0 0 * * *"), WithPolicy(LineBasedPolicy(10))}},
+		{name: "schedule after policy", opts: []OptionFunc{WithSizeBasedPolicy(10), WithSchedule(daily)}, wantTimer: true},
+		{name: "explicit timer", opts: []OptionFunc{WithRotationTimer(daily), WithTimeBasedPolicy(func(int64) bool { return false })}, wantTimer: true},
+		{name: "explicit timer after schedule", opts: []OptionFunc{WithSchedule(daily), WithRotationTimer(daily), WithStrictSizeBasedPolicy(10)}, wantTimer: true},
+	}
+	for _, te := range tt {
+		te := te
+		t.Run(te.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := createTmpDir()
+			defer dir.removeAll()
+
+			w, err := NewWriter(string(dir), "test.log", te.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+
+			if got := w.opt.timer != nil; got != te.wantTimer {
+				t.Errorf("timer got %v, want %v", got, te.wantTimer)
+			}
+		})
+	}
+}
+
+func TestWriter_RotationTimer_StaleFile(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	// the file written before the last boundary of the schedule
+	path := filepath.Join(string(dir), "test.log")
+	if err := ioutil.WriteFile(path, []byte("aaaaaaaaaa"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	var openedAt int64
+	policy := func(f FileState) bool {
+		atomic.CompareAndSwapInt64(&openedAt, 0, f.OpenedAt)
+		return SchedulePolicy(Daily(0, 0, time.Local))(f)
+	}
+	w, err := NewWriter(string(dir), "test.log", WithPolicy(policy), WithRotationTimer(Daily(0, 0, time.Local)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// rotate right away
+	if err := dir.waitFileCreated(2*time.Second, "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := retry(time.Second, 10*time.Millisecond, func() error {
+		return containsNCount("a", 10, dir, "test.log.1")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt64(&openedAt); got != modTime.Unix() {
+		t.Errorf("OpenedAt got %v, want %v", time.Unix(got, 0), modTime)
+	}
+}
+
+func TestWriter_FileState(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local)
+	clock := func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	var got FileState
+	policy := func(fileState FileState) bool {
+		got = fileState
+		return false
+	}
+	w, err := NewWriter(string(dir), "test.log", WithPolicy(policy), WithNowFunc(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	fmt.Fprint(w, "a\nb\n")
+	fmt.Fprint(w, "c")
+
+	want := FileState{
+		OpenedAt:     time.Date(2026, 10, 17, 10, 0, 1, 0, time.Local).Unix(),
+		Size:         5,
+		Lines:        2,
+		Writes:       2,
+		FirstWriteAt: time.Date(2026, 10, 17, 10, 0, 2, 0, time.Local),
+		LastWriteAt:  time.Date(2026, 10, 17, 10, 0, 4, 0, time.Local),
+		Now:          time.Date(2026, 10, 17, 10, 0, 5, 0, time.Local),
+	}
+	if got.OpenedAt != want.OpenedAt || got.Size != want.Size || got.Lines != want.Lines || got.Writes != want.Writes ||
+		!got.FirstWriteAt.Equal(want.FirstWriteAt) || !got.LastWriteAt.Equal(want.LastWriteAt) || !got.Now.Equal(want.Now) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestWriter_Rotate_LineBased(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	// lines of the existing file are counted
+	if err := ioutil.WriteFile(filepath.Join(string(dir), "test.log"), []byte("a\na\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(string(dir), "test.log", WithLineBasedPolicy(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	fmt.Fprint(w, "a")
+	if err := dir.waitFileNotCreated(200*time.Millisecond, "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(w, "a\n")
+	if err := dir.waitFileCreated(time.Second, "test.log", "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := retry(time.Second, 10*time.Millisecond, func() error { return containsNCount("\n", 3, dir, "test.log.1") }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_LineCount(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name      string
+		opts      []OptionFunc
+		wantLines int64
+	}{
+		{name: "not counted", wantLines: 0},
+		{name: "WithLineCount", opts: []OptionFunc{WithLineCount()}, wantLines: 2},
+		{name: "WithLineBasedPolicy", opts: []OptionFunc{WithLineBasedPolicy(10)}, wantLines: 2},
+	}
+	for _, te := range tt {
+		te := te
+		t.Run(te.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := createTmpDir()
+			defer dir.removeAll()
+
+			if err := ioutil.WriteFile(filepath.Join(string(dir), "test.log"), []byte("a\na\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			w, err := NewWriter(string(dir), "test.log", te.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+
+			fileState := func() FileState {
+				w.mu.RLock()
+				defer w.mu.RUnlock()
+				return w.fileState()
+			}
+			if got := fileState().Lines; got != te.wantLines {
+				t.Errorf("Lines got %v, want %v", got, te.wantLines)
+			}
+			// the state is kept by reopening the same file
+			if err := writeNCount(w, "b\n", 2); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Reopen(); err != nil {
+				t.Fatal(err)
+			}
+			if got := fileState(); got.Size != 6 || got.Lines != te.wantLines+1 {
+				t.Errorf("Size, Lines got %v, %v, want 6, %v", got.Size, got.Lines, te.wantLines+1)
+			}
+		})
+	}
+}
+
+func TestWriter_Rotate_RecordBoundary(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	const nBytes = 10
+
+	w, err := NewWriter(string(dir), "test.log", WithSizeBasedPolicy(nBytes), WithRecordBoundary())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// a record split into the multiple Write calls
+	fmt.Fprint(w, "aaaaaaaa")
+	fmt.Fprint(w, "aaaaaaaa")
+	if err := dir.waitFileNotCreated(10*time.Millisecond, "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(w, "aaaa\n")
+	// rotated synchronously
+	if err := dir.waitFileCreated(time.Millisecond, "test.log", "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(w, "b\n")
+
+	if err := containsNCount("a", 20, dir, "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("b", 1, dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_Rotate_RecordBoundary_BeforeWrite(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	const nBytes = 10
+
+	w, err := NewWriter(string(dir), "test.log", WithSizeBasedPolicy(nBytes), WithRecordBoundary())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	fmt.Fprint(w, "aaaaaaaa\n")
+	// a record which would push the file over the size
+	fmt.Fprint(w, strings.Repeat("b", 20)+"\n")
+	if err := dir.waitFileCreated(time.Millisecond, "test.log", "test.log.1", "test.log.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := containsNCount("a", 8, dir, "test.log.2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("b", 20, dir, "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(filepath.Join(string(dir), "test.log")); err != nil || fi.Size() != 0 {
+		t.Errorf("test.log got %v, %v, want empty", fi, err)
+	}
+}
+
+func TestWriter_Rotate_RecordBoundary_Parallel(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	const nGoroutines = 10
+	const nRecords = 100
+
+	w, err := NewWriter(string(dir), "test.log", WithSizeBasedPolicy(100), WithKeeps(nGoroutines*nRecords), WithRecordBoundary())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = nGroutinesDo(nGoroutines, func() error {
+		for i := 0; i < nRecords; i++ {
+			if _, err := fmt.Fprint(w, "record\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fis, err := ioutil.ReadDir(string(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range fis {
+		b, err := ioutil.ReadFile(filepath.Join(string(dir), fi.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) > 0 && b[len(b)-1] != '\n' {
+			t.Errorf("%s does not end with a newline", fi.Name())
+		}
+		if len(b) > 100+len("record\n") {
+			t.Errorf("%s is %d bytes", fi.Name(), len(b))
+		}
+	}
+}
+
+func TestWriter_Rotate_StrictSize(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	const nBytes = 100
+	const nGoroutines = 10
+	const nWrites = 50
+
+	w, err := NewWriter(string(dir), "test.log", WithStrictSizeBasedPolicy(nBytes), WithKeeps(nGoroutines*nWrites))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = nGroutinesDo(nGoroutines, func() error {
+		for i := 0; i < nWrites; i++ {
+			if _, err := fmt.Fprint(w, "aaaaaaa"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a single write larger than the size
+	if _, err := fmt.Fprint(w, strings.Repeat("b", nBytes+1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fis, err := ioutil.ReadDir(string(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filenames := make([]string, 0, len(fis))
+	for _, fi := range fis {
+		filenames = append(filenames, fi.Name())
+		if fi.Name() == "test.log.1" {
+			if fi.Size() != nBytes+1 {
+				t.Errorf("%s is %d bytes", fi.Name(), fi.Size())
+			}
+			continue
+		}
+		if fi.Size() > nBytes {
+			t.Errorf("%s is %d bytes", fi.Name(), fi.Size())
+		}
+	}
+	if err := containsNCount("a", nGoroutines*nWrites*7, dir, filenames...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_Rotate_StrictSize_RecordBoundary(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	w, err := NewWriter(string(dir), "test.log", WithStrictSizeBasedPolicy(8), WithRecordBoundary())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	fmt.Fprint(w, "rec1\n")
+	// a record split into the multiple Write calls, which pushes the file over the size
+	fmt.Fprint(w, "rec2-part1 ")
+	fmt.Fprint(w, "part2\n")
+	if err := dir.waitFileCreated(time.Millisecond, "test.log", "test.log.1", "test.log.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"test.log.2": "rec1\n", "test.log.1": "rec2-part1 part2\n", "test.log": ""} {
+		b, err := ioutil.ReadFile(filepath.Join(string(dir), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("%s got %q, want %q", name, b, want)
+		}
+	}
+}
+
+func TestWriter_Rotate_StrictSize_ReplacedPolicy(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	w, err := NewWriter(string(dir), "test.log", WithStrictSizeBasedPolicy(10), WithPolicy(LineBasedPolicy(1000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if w.opt.syncRotation() {
+		t.Error("the Writer rotates synchronously")
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := writeNBytes(w, "a", 6); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := dir.waitFileNotCreated(10*time.Millisecond, "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(filepath.Join(string(dir), "test.log")); err != nil || fi.Size() != 30 {
+		t.Errorf("test.log got %v, %v, want 30 bytes", fi, err)
+	}
+}
+
+func TestWriter_Rotate_TimeNamerSameName(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	errs := make(chan error, 100)
+	now := func() time.Time { return time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local) }
+	w, err := NewWriter(string(dir), "test.log", WithSizeBasedPolicy(10), WithTimeLayout("20060102T150405"), WithNowFunc(now),
+		WithKeeps(3), WithSyncRotation(), WithErrorHandler(func(err error) { errs <- err }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// rotations within the same second
+	for i := 0; i < 5; i++ {
+		if _, err := writeNBytes(w, strconv.Itoa(i), 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(errs) != 0 {
+		t.Fatalf("unexpected error %v", <-errs)
+	}
+	if err := dir.waitFileNotCreated(0, "test.log.20261017T100000", "test.log.20261017T100000.1"); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"test.log.20261017T100000.2", "test.log.20261017T100000.3", "test.log.20261017T100000.4"} {
+		if err := containsNCount(strconv.Itoa(i+2), 10, dir, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// dailyNamer names the rotated files with the date and the 3-digit sequence number, e.g. app.log > app-20261017.log > app-20261017-001.log
+type dailyNamer struct{}
+
+func (dailyNamer) CurrentName(base string) string { return base }
+
+func (dailyNamer) RotatedName(current string, _ int, t time.Time) string {
+	return strings.TrimSuffix(current, ".log") + "-" + t.Format("20060102") + ".log"
+}
+
+func (dailyNamer) Glob(current string) string { return strings.TrimSuffix(current, ".log") + "-*.log" }
+
+func (dailyNamer) Parse(current, path string) (int64, bool) {
+	s := strings.TrimSuffix(strings.TrimPrefix(path, strings.TrimSuffix(current, ".log")+"-"), ".log")
+	var seq int64
+	if i := strings.Index(s, "-"); i >= 0 {
+		n, err := strconv.ParseInt(s[i+1:], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		s, seq = s[:i], n
+	}
+	t, err := time.ParseInLocation("20060102", s, time.Local)
+	if err != nil {
+		return 0, false
+	}
+	return t.UnixNano() + seq, true
+}
+
+func (dailyNamer) Shift() bool { return false }
+
+func (dailyNamer) Sequenced(current, rotated string, seq int) string {
+	return fmt.Sprintf("%s-%03d.log", strings.TrimSuffix(rotated, ".log"), seq)
+}
+
+func TestWriter_Rotate_Sequencer(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	now := func() time.Time { return time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local) }
+	w, err := NewWriter(string(dir), "app.log", WithNamer(dailyNamer{}), WithNowFunc(now), WithKeeps(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// rotations within the same day
+	for i := 0; i < 3; i++ {
+		if _, err := writeNBytes(w, strconv.Itoa(i), 10); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := dir.waitFileNotCreated(0, "app-20261017.log"); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"app-20261017-001.log", "app-20261017-002.log"} {
+		if err := containsNCount(strconv.Itoa(i+1), 10, dir, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWriter_Rotate_SyncRotation(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	now := func() time.Time { return time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local) }
+	w, err := NewWriter(string(dir), "test.log", WithSizeBasedPolicy(10), WithNamer(TimeNamer{Layout: "150405", DirLayout: "20060102"}), WithNowFunc(now), WithSyncRotation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// the directory of the rotated file can not be created
+	if err := touchFiles(dir, "20261017"); err != nil {
+		t.Fatal(err)
+	}
+	n, err := writeNBytes(w, "a", 10)
+	if n != 10 {
+		t.Errorf("n got %v, want 10", n)
+	}
+	var rerr *RotateError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("want *RotateError, got %v", err)
+	}
+	if rerr.Op != "mkdir" || rerr.Path != filepath.Join(string(dir), "20261017") || !errors.Is(err, ErrMkdir) {
+		t.Errorf("unexpected error %+v", rerr)
+	}
+
+	// retry on the next write
+	if err := os.Remove(filepath.Join(string(dir), "20261017")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writeNBytes(w, "a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("a", 11, dir, filepath.Join("20261017", "test.log.100000")); err != nil {
+		t.Fatal(err)
+	}
+	if err := emptyFile(dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_ErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	errs := make(chan error, 10)
+	now := func() time.Time { return time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local) }
+	w, err := NewWriter(string(dir), "test.log", WithSizeBasedPolicy(10), WithNamer(TimeNamer{Layout: "150405", DirLayout: "20060102"}), WithNowFunc(now),
+		WithErrorHandler(func(err error) { errs <- err }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// the directory of the rotated file can not be created
+	if err := touchFiles(dir, "20261017"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writeNBytes(w, "a", 10); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrMkdir) || errors.Is(err, ErrRemove) {
+			t.Errorf("unexpected error %+v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the error")
+	}
+}
+
+func TestWriter_RotateHooks(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	beforeErr := errors.New("before")
+	var before []RotateEvent
+	var after []RotateEvent
+	var rotatedExists bool
+	notified := make(chan RotateEvent, 10)
+	errs := make(chan error, 10)
+	w, err := NewWriter(string(dir), "test.log", WithSizeBasedPolicy(1024),
+		WithBeforeRotate(func(ev RotateEvent) error {
+			before = append(before, ev)
+			if len(before) == 1 {
+				return beforeErr
+			}
+			return nil
+		}),
+		WithAfterRotate(func(ev RotateEvent) error {
+			after = append(after, ev)
+			_, err := os.Stat(ev.RotatedPath)
+			rotatedExists = err == nil
+			return errors.New("after")
+		}),
+		WithOnRotate(func(ev RotateEvent) { notified <- ev }),
+		WithErrorHandler(func(err error) { errs <- err }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err := writeNBytes(w, "a", 10); err != nil {
+		t.Fatal(err)
+	}
+	// the error of the BeforeRotate aborts the rotation
+	if err := w.Rotate(); !errors.Is(err, ErrHook) || !errors.Is(err, beforeErr) {
+		t.Fatalf("unexpected error %+v", err)
+	}
+	if err := dir.waitFileNotCreated(0, "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != 0 {
+		t.Errorf("after got %v, want no calls", len(after))
+	}
+
+	// the error of the AfterRotate is reported to the error handler
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(before) != 2 || before[1].Path != filepath.Join(string(dir), "test.log") || before[1].Size != 10 || before[1].RotatedPath != "" {
+		t.Errorf("unexpected before events %+v", before)
+	}
+	if len(after) != 1 {
+		t.Fatalf("after got %v, want 1 call", len(after))
+	}
+	ev := after[0]
+	if ev.Path != filepath.Join(string(dir), "test.log") || ev.RotatedPath != filepath.Join(string(dir), "test.log.1") || ev.Size != 10 {
+		t.Errorf("unexpected after event %+v", ev)
+	}
+	if ev.OpenedAt.IsZero() || ev.ClosedAt.Before(ev.OpenedAt) {
+		t.Errorf("unexpected timestamps %+v", ev)
+	}
+	if !rotatedExists {
+		t.Errorf("rotated file does not exist in the AfterRotate")
+	}
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrHook) {
+			t.Errorf("unexpected error %+v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the error")
+	}
+	select {
+	case got := <-notified:
+		if got != ev {
+			t.Errorf("on rotate got %+v, want %+v", got, ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the notification")
+	}
+}
+
+func TestWriter_Buffer(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	w, err := NewWriter(string(dir), "test.log", WithBuffer(10, 0), WithSizeBasedPolicy(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// buffered
+	if err := writeNCount(w, "a", 9); err != nil {
+		t.Fatal(err)
+	}
+	if err := emptyFile(dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+	// flushed when the buffer is full
+	if err := writeNCount(w, "a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("a", 10, dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+	// Flush
+	if err := writeNCount(w, "b", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("b", 5, dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+	// flushed to the rotated file
+	if err := writeNCount(w, "c", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("c", 5, dir, "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	// Sync
+	if err := writeNCount(w, "d", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("d", 5, dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+	// Close
+	if err := writeNCount(w, "e", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("e", 5, dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("f")); err != ErrClosed {
+		t.Errorf("got %v, want ErrClosed", err)
+	}
+}
+
+func TestWriter_Buffer_FlushError(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	errs := make(chan error, 10)
+	w, err := NewWriter(string(dir), "test.log", WithBuffer(10, 0), WithSizeBasedPolicy(1024),
+		WithErrorHandler(func(err error) { errs <- err }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := writeNCount(w, "a", 5); err != nil {
+		t.Fatal(err)
+	}
+	// make the flush fail by a closed file
+	closed, err := os.Open(filepath.Join(string(dir), "test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	w.mu.Lock()
+	f := w.f
+	w.f = closed
+	w.mu.Unlock()
+
+	n, err := w.Write([]byte("aaaaa"))
+	if n != 5 || err != nil {
+		t.Errorf("got %v, %v, want 5, nil", n, err)
+	}
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrWrite) {
+			t.Errorf("got %v, want ErrWrite", err)
+		}
+	default:
+		t.Error("the flush error is not reported")
+	}
+	w.mu.RLock()
+	size := w.fileState().Size
+	w.mu.RUnlock()
+	if size != 10 {
+		t.Errorf("size got %v, want 10", size)
+	}
+
+	// the buffered data is written once by the next flush
+	w.mu.Lock()
+	w.f = f
+	w.mu.Unlock()
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("a", 10, dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_Buffer_FlushInterval(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	w, err := NewWriter(string(dir), "test.log", WithBuffer(1024, 50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := writeNCount(w, "a", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := retry(3*time.Second, 10*time.Millisecond, func() error {
+		return containsNCount("a", 10, dir, "test.log")
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_Buffer_Parallel(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	w, err := NewWriter(string(dir), "test.log", WithBuffer(7, 0), WithSizeBasedPolicy(100), WithKeeps(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nGroutinesDo(10, func() error { return writeNCount(w, "a", 100) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// no data is lost by the rotations
+	matches, err := filepath.Glob(filepath.Join(string(dir), "test.log*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, m := range matches {
+		files = append(files, filepath.Base(m))
+	}
+	if err := containsNCount("a", 1000, dir, files...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_SyncPolicy(t *testing.T) {
+	t.Parallel()
+
+	// the sync writes the buffered data to the file
+	tt := []struct {
+		name   string
+		policy SyncPolicy
+		// whether the data is written to the file after 5 bytes and 10 bytes
+		after5, after10 bool
+	}{
+		{name: "never", policy: SyncNever},
+		{name: "on rotate", policy: SyncOnRotate},
+		{name: "every write", policy: SyncEveryWrite, after5: true, after10: true},
+		{name: "every bytes", policy: SyncEveryBytes(10), after10: true},
+	}
+	for _, te := range tt {
+		te := te
+		t.Run(te.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := createTmpDir()
+			defer dir.removeAll()
+
+			w, err := NewWriter(string(dir), "test.log", WithBuffer(1024, 0), WithSizeBasedPolicy(1024), WithSyncPolicy(te.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+
+			check := func(written bool, n int) error {
+				if written {
+					return containsNCount("a", n, dir, "test.log")
+				}
+				return emptyFile(dir, "test.log")
+			}
+			if err := writeNCount(w, "a", 5); err != nil {
+				t.Fatal(err)
+			}
+			if err := check(te.after5, 5); err != nil {
+				t.Fatal(err)
+			}
+			if err := writeNCount(w, "a", 5); err != nil {
+				t.Fatal(err)
+			}
+			if err := check(te.after10, 10); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Rotate(); err != nil {
+				t.Fatal(err)
+			}
+			if err := containsNCount("a", 10, dir, "test.log.1"); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestWriter_SyncPolicy_Interval(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	w, err := NewWriter(string(dir), "test.log", WithBuffer(1024, 0), WithSyncPolicy(SyncEveryInterval(50*time.Millisecond)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := writeNCount(w, "a", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := retry(3*time.Second, 10*time.Millisecond, func() error {
+		return containsNCount("a", 10, dir, "test.log")
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_SyncPolicy_CopyTruncate(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	now := func() time.Time { return time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local) }
+	namer := TimeNamer{Layout: "15-04-05", DirLayout: "2006-01-02"}
+	w, err := NewWriter(string(dir), "test.log", WithCopyTruncate(), WithNamer(namer), WithNowFunc(now), WithSyncPolicy(SyncOnRotate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := writeNCount(w, "a", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("a", 10, dir, filepath.Join("2026-10-17", "test.log.10-00-00")); err != nil {
+		t.Fatal(err)
+	}
+	if err := emptyFile(dir, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_Rotate_Compression(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	const nBytes = 100
+	const keeps = 2
+
+	w, err := NewWriter(string(dir), "test.log", WithKeeps(keeps), WithSizeBasedPolicy(int64(nBytes)), WithCompression(Gzip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := writeNCount(w, "a", nBytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.waitFileCreated(time.Second, "test.log", "test.log.1.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeNCount(w, "b", nBytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.waitFileCreated(time.Second, "test.log", "test.log.1.gz", "test.log.2.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeNCount(w, "c", nBytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.waitFileNotCreated(500*time.Millisecond, "test.log.1", "test.log.2", "test.log.3", "test.log.3.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCountGzip("c", nBytes, dir, "test.log.1.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCountGzip("b", nBytes, dir, "test.log.2.gz"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// gatedCompressor blocks the compression until the release is closed
+type gatedCompressor struct {
+	Compressor
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (c gatedCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	select {
+	case c.entered <- struct{}{}:
+	default:
+	}
+	<-c.release
+	return c.Compressor.NewWriter(w)
+}
+
+func TestWriter_Rotate_WhileCompressing(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	c := gatedCompressor{Compressor: Gzip, entered: make(chan struct{}, 1), release: make(chan struct{})}
+	w, err := NewWriter(string(dir), "test.log", WithSizeBasedPolicy(1024), WithCompression(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := writeNCount(w, "a", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	<-c.entered
+
+	// the rotation is not blocked by the compression, which renames test.log.1 to test.log.2
+	if err := writeNCount(w, "b", 10); err != nil {
+		t.Fatal(err)
+	}
+	rotated := make(chan error, 1)
+	go func() { rotated <- w.Rotate() }()
+	select {
+	case err := <-rotated:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the rotation is blocked by the compression")
+	}
+
+	close(c.release)
+	if err := dir.waitFileCreated(3*time.Second, "test.log.1.gz", "test.log.2.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCountGzip("b", 10, dir, "test.log.1.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCountGzip("a", 10, dir, "test.log.2.gz"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_Rotate_CompressDelay(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	const nBytes = 100
+	const keeps = 3
+
+	w, err := NewWriter(string(dir), "test.log", WithKeeps(keeps), WithSizeBasedPolicy(int64(nBytes)), WithCompression(Zstd), WithCompressDelay(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := writeNCount(w, "a", nBytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.waitFileCreated(time.Second, "test.log", "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeNCount(w, "b", nBytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.waitFileCreated(time.Second, "test.log", "test.log.1", "test.log.2.zst"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.waitFileNotCreated(200*time.Millisecond, "test.log.1.zst", "test.log.2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("b", nBytes, dir, "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_MaxAge(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	const nBytes = 100
+	const keeps = 5
+
+	if err := touchFiles(dir, "test.log.1", "test.log.2"); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(string(dir), "test.log.2"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWriter(string(dir), "test.log", WithKeeps(keeps), WithSizeBasedPolicy(int64(nBytes)), WithMaxAge(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// removed on rotation
+	if err := writeNCount(w, "a", nBytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.waitFileCreated(time.Second, "test.log", "test.log.1", "test.log.2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.waitFileNotCreated(200*time.Millisecond, "test.log.3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("a", nBytes, dir, "test.log.1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_MaxAge_Sweep(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	if err := touchFiles(dir, "test.log.1", "test.log.2"); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Second)
+	if err := os.Chtimes(filepath.Join(string(dir), "test.log.2"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWriter(string(dir), "test.log", WithMaxAge(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := dir.waitFileNotCreated(1500*time.Millisecond, "test.log.2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.waitFileCreated(time.Millisecond, "test.log"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_MaxTotalSize(t *testing.T) {
+	t.Parallel()
+
+	dir := createTmpDir()
+	defer dir.removeAll()
+
+	const nBytes = 100
+	const keeps = 5
+
+	w, err := NewWriter(string(dir), "test.log", WithKeeps(keeps), WithSizeBasedPolicy(int64(nBytes)), WithMaxTotalSize(nBytes*2+nBytes/2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	for _, s := range []string{"a", "b", "c"} {
+		if err := writeNCount(w, s, nBytes); err != nil {
+			t.Fatal(err)
+		}
+		if err := retry(time.Second, 10*time.Millisecond, func() error { return containsNCount(s, nBytes, dir, "test.log.1") }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := dir.waitFileNotCreated(200*time.Millisecond, "test.log.3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := containsNCount("b", nBytes, dir, "test.log.2"); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestWriter_Rotate_WhileOpeningFileFromAnotherProcess(t *testing.T) {
 	t.Parallel()
 
@@ -281,7 +1743,94 @@ func Test_pushAndShiftKeeps(t *testing.T) {
 			if err := touchFiles(dir, te.existFiles...); err != nil {
 				t.Fatal(err)
 			}
-			if err := pushAndShiftKeeps(filepath.Join(string(dir), te.filename), te.keeps); err != nil {
+			var opt option
+			opt.apply(WithKeeps(te.keeps))
+			if _, err := pushAndShiftKeeps(filepath.Join(string(dir), te.filename), opt, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+			if err := dir.waitFileCreated(time.Millisecond, te.wantIncludes...); err != nil {
+				t.Error(err)
+			}
+			if err := dir.waitFileNotCreated(time.Millisecond, te.wantNotIncludes...); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func Test_pushAndShiftKeeps_TimeNamer(t *testing.T) {
+	t.Parallel()
+
+	const layout = "20060102T150405"
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local)
+
+	tt := []struct {
+		filename        string
+		existFiles      []string
+		keeps           int
+		wantIncludes    []string
+		wantNotIncludes []string
+	}{
+		{
+			filename:        "test.log",
+			existFiles:      []string{"test.log"},
+			keeps:           2,
+			wantIncludes:    []string{"test.log.20261017T100000"},
+			wantNotIncludes: []string{"test.log"},
+		},
+		{
+			filename:        "test.log",
+			existFiles:      []string{"test.log"},
+			keeps:           0,
+			wantNotIncludes: []string{"test.log", "test.log.20261017T100000"},
+		},
+		{
+			filename:        "test.log",
+			existFiles:      []string{"test.log", "test.log.20261017T090000"},
+			keeps:           2,
+			wantIncludes:    []string{"test.log.20261017T100000", "test.log.20261017T090000"},
+			wantNotIncludes: []string{"test.log"},
+		},
+		{
+			filename:        "test.log",
+			existFiles:      []string{"test.log", "test.log.20261016T090000", "test.log.20261017T090000", "test.log.foo"},
+			keeps:           2,
+			wantIncludes:    []string{"test.log.20261017T100000", "test.log.20261017T090000", "test.log.foo"},
+			wantNotIncludes: []string{"test.log", "test.log.20261016T090000"},
+		},
+		{
+			filename:        "test.log",
+			existFiles:      []string{"test.log.20261016T090000", "test.log.20261017T090000"},
+			keeps:           1,
+			wantIncludes:    []string{"test.log.20261016T090000", "test.log.20261017T090000"},
+			wantNotIncludes: []string{"test.log", "test.log.20261017T100000"},
+		},
+		{
+			filename:        "test.log",
+			existFiles:      []string{"test.log", "test.log.20261017T100000"},
+			keeps:           2,
+			wantIncludes:    []string{"test.log.20261017T100000", "test.log.20261017T100000.1"},
+			wantNotIncludes: []string{"test.log"},
+		},
+		{
+			filename:        "test.log",
+			existFiles:      []string{"test.log", "test.log.20261017T100000", "test.log.20261017T100000.1"},
+			keeps:           2,
+			wantIncludes:    []string{"test.log.20261017T100000.1", "test.log.20261017T100000.2"},
+			wantNotIncludes: []string{"test.log", "test.log.20261017T100000"},
+		},
+	}
+	for i, te := range tt {
+		t.Run(fmt.Sprintf("#%d", i), func(t *testing.T) {
+			dir := createTmpDir()
+			defer dir.removeAll()
+
+			if err := touchFiles(dir, te.existFiles...); err != nil {
+				t.Fatal(err)
+			}
+			var opt option
+			opt.apply(WithKeeps(te.keeps), WithTimeLayout(layout))
+			if _, err := pushAndShiftKeeps(filepath.Join(string(dir), te.filename), opt, now); err != nil {
 				t.Fatal(err)
 			}
 			if err := dir.waitFileCreated(time.Millisecond, te.wantIncludes...); err != nil {
@@ -390,6 +1939,10 @@ func writeNCount(w io.Writer, s string, nCount int) error {
 	return nil
 }
 
+func writeNBytes(w io.Writer, s string, nCount int) (int, error) {
+	return w.Write([]byte(strings.Repeat(s, nCount)))
+}
+
 func containsNCount(s string, nCount int, d tmpDir, filenames ...string) error {
 	var buf bytes.Buffer
 	for _, fn := range filenames {
@@ -407,6 +1960,26 @@ func containsNCount(s string, nCount int, d tmpDir, filenames ...string) error {
 	return nil
 }
 
+func containsNCountGzip(s string, nCount int, d tmpDir, filename string) error {
+	f, err := os.Open(filepath.Join(string(d), filename))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return err
+	}
+	if count := strings.Count(string(b), s); count != nCount {
+		return fmt.Errorf("%s contains %d count %v", s, count, filename)
+	}
+	return nil
+}
+
 func emptyFile(d tmpDir, filename string) error {
 	fi, err := os.Stat(filepath.Join(string(d), filename))
 	if err != nil {